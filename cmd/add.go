@@ -3,55 +3,241 @@ package cmd
 import (
 	"fmt"
 	"mpass/internal/models"
-	"mpass/internal/storage"
+	"mpass/internal/strength"
 	"mpass/internal/ui"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
-var addCmd = &cobra.Command{
-	Use:   "add",
-	Short: "Add a new password entry",
-	Long:  "Add a new password entry with username, URL, and password",
-	RunE:  runAdd,
+var (
+	addCmd = &cobra.Command{
+		Use:   "add",
+		Short: "Add a new password entry",
+		Long:  "Add a new password entry with username, URL, and password, or with --type a secure note, payment card, identity, API token or SSH key",
+		RunE:  runAdd,
+	}
+	addFromClipboard bool
+	addGenerate      bool
+	showGenerated    bool
+	addType          string
+)
+
+// init registers the flags of the add command, including the generation policy flags used
+// with --generate and the flags that set notes, custom fields, the folder and tags.
+func init() {
+	addCmd.Flags().BoolVar(&addFromClipboard, "from-clipboard", false, "Take the password from the clipboard instead of prompting for it")
+	addCmd.Flags().BoolVar(&addGenerate, "generate", false, "Generate the password instead of prompting for it")
+	addCmd.Flags().StringVar(&addType, "type", string(models.TypeLogin), "Type of entry: "+typeNames())
+	addShowFlag(addCmd)
+	addPolicyFlags(addCmd)
+	addFieldFlags(addCmd, false)
+	addPlacementFlags(addCmd)
 }
 
-func runAdd(_ *cobra.Command, _ []string) error {
-	// Get master password
-	masterPassword, err := ui.PromptPassword("Enter master password:")
-	if err != nil {
-		return fmt.Errorf("failed to get master password: %w", err)
+// addShowFlag registers --show on a command that can save a generated password.
+func addShowFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&showGenerated, "show", false, "Print the generated password")
+}
+
+// checkGenerateFlags fails if policy flags or --show are given without the flag that
+// enables generation, named by generateFlag.
+func checkGenerateFlags(cmd *cobra.Command, generate bool, generateFlag string) error {
+	if generate {
+		return nil
+	}
+	if name := changedPolicyFlag(cmd); name != "" {
+		return fmt.Errorf("--%s can only be used with --%s", name, generateFlag)
+	}
+	if showGenerated {
+		return fmt.Errorf("--show can only be used with --%s", generateFlag)
 	}
+	return nil
+}
 
-	// Get entry details
-	username, err := ui.PromptInput("Username:")
-	if err != nil {
-		return fmt.Errorf("failed to get username: %w", err)
+// printGenerated prints a generated password if --show was given.
+func printGenerated(password string) {
+	if showGenerated {
+		fmt.Println("🔑 Generated password:", password)
 	}
+}
 
-	url, err := ui.PromptInput("URL:")
+func runAdd(cmd *cobra.Command, _ []string) error {
+	if err := checkGenerateFlags(cmd, addGenerate, "generate"); err != nil {
+		return err
+	}
+	if addGenerate && addFromClipboard {
+		return fmt.Errorf("--generate cannot be combined with --from-clipboard")
+	}
+	entryType, err := models.ParseType(addType)
 	if err != nil {
-		return fmt.Errorf("failed to get URL: %w", err)
+		return err
+	}
+	schema := entryType.Schema()
+	if schema.Password == "" && (addGenerate || addFromClipboard) {
+		return fmt.Errorf("a %s has no password to generate or paste", schema.Label)
+	}
+	placement, err := entryFilter()
+	if err != nil {
+		return err
 	}
 
-	password, err := ui.PromptPassword("Password:")
+	// Unlock vault
+	vault, masterPassword, err := openVault()
 	if err != nil {
-		return fmt.Errorf("failed to get password: %w", err)
+		return err
 	}
 
-	// Create entry
+	// Get entry details
 	entry := models.PasswordEntry{
-		Username: username,
-		URL:      url,
-		Password: password,
+		Type:   entryType,
+		Folder: placement.Folder,
+		Tags:   placement.Tags,
+	}
+	if entryType != models.TypeLogin {
+		if entry.Name, err = ui.PromptInput("Name:"); err != nil {
+			return fmt.Errorf("failed to get name: %w", err)
+		}
+	}
+	if schema.Username != "" {
+		if entry.Username, err = ui.PromptInput(schema.Username + ":"); err != nil {
+			return fmt.Errorf("failed to get %s: %w", strings.ToLower(schema.Username), err)
+		}
+	}
+	if schema.URL != "" {
+		if entry.URL, err = ui.PromptInput(schema.URL + ":"); err != nil {
+			return fmt.Errorf("failed to get %s: %w", strings.ToLower(schema.URL), err)
+		}
+	}
+	if schema.Password != "" {
+		if entry.Password, err = readEntryPassword(cmd, schema.Password); err != nil {
+			return err
+		}
+	}
+	if entryType == models.TypeLogin {
+		if err := checkStrength(entry.Password); err != nil {
+			return err
+		}
+	}
+
+	if err := applyFieldFlags(cmd, &entry); err != nil {
+		return err
+	}
+	if err := promptTypedFields(&entry); err != nil {
+		return err
 	}
 
 	// Save entry
-	vault := storage.NewVault()
 	if err := vault.AddEntry(entry, masterPassword); err != nil {
 		return fmt.Errorf("failed to add entry: %w", err)
 	}
 
-	fmt.Println("✅ Password entry added successfully!")
+	if entryType == models.TypeLogin {
+		fmt.Println("✅ Password entry added successfully!")
+	} else {
+		fmt.Printf("✅ %s %s added successfully!\n", upperFirst(schema.Label), entry.Title())
+	}
+	if addGenerate {
+		printGenerated(entry.Password)
+	}
+	return nil
+}
+
+// promptTypedFields asks for the fields of the entry's type that were not given with the
+// field flags, and for the text of a secure note without --notes. Multi-line fields and
+// the note are read until end of input. Fields left blank are not stored.
+func promptTypedFields(entry *models.PasswordEntry) error {
+	schema := entry.Kind().Schema()
+	for _, spec := range schema.Fields {
+		if _, ok := entry.Field(spec.Name); ok {
+			continue
+		}
+		var value string
+		var err error
+		switch {
+		case spec.Multiline:
+			value, err = ui.PromptMultiline(spec.Name+":", spec.Concealed)
+			value = fieldText(value)
+		case spec.Concealed:
+			value, err = ui.PromptPassword(spec.Name + ":")
+		default:
+			value, err = ui.PromptInput(spec.Name + ":")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", strings.ToLower(spec.Name), err)
+		}
+		if value != "" {
+			entry.SetField(models.CustomField{Name: spec.Name, Value: value, Concealed: spec.Concealed})
+		}
+	}
+
+	if schema.Copy == models.CopyNotes && entry.Notes == "" {
+		notes, err := ui.PromptMultiline("Note:", false)
+		if err != nil {
+			return fmt.Errorf("failed to get note: %w", err)
+		}
+		entry.Notes = strings.TrimRight(notes, "\r\n")
+	}
+	return nil
+}
+
+// upperFirst returns s with its first letter in upper case, for labels starting a sentence.
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// readEntryPassword returns the password for a new entry: a generated one with --generate,
+// the clipboard contents with --from-clipboard, otherwise the password typed by the user at
+// a prompt with the given label.
+func readEntryPassword(cmd *cobra.Command, label string) (string, error) {
+	if addGenerate {
+		return generatePassword(cmd)
+	}
+	if !addFromClipboard {
+		password, err := ui.PromptPassword(label + ":")
+		if err != nil {
+			return "", fmt.Errorf("failed to get %s: %w", strings.ToLower(label), err)
+		}
+		return password, nil
+	}
+
+	backend, err := openClipboard()
+	if err != nil {
+		return "", err
+	}
+	password, err := backend.Read()
+	if err != nil {
+		return "", fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("the clipboard is empty")
+	}
+	return password, nil
+}
+
+// checkStrength prints the strength estimate of a password about to be saved in an entry and
+// fails if its score is below passwords.min_score from the config file. An empty password is
+// always rejected.
+func checkStrength(password string) error {
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	result := strength.Estimate(password)
+	fmt.Printf("🔎 Strength: %s\n", result)
+	if result.Warning != "" {
+		fmt.Printf("⚠️  Weakness: %s\n", result.Warning)
+	}
+	if result.Score < cfg.Passwords.MinScore {
+		return fmt.Errorf("password is too weak: score %d is below the minimum of %d set in the config file", result.Score, cfg.Passwords.MinScore)
+	}
 	return nil
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mpass/internal/agent"
+	"mpass/internal/config"
+	"mpass/internal/proc"
+	"mpass/internal/storage"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// agentStartTimeout is how long unlock waits for a freshly started agent to come up.
+const agentStartTimeout = 3 * time.Second
+
+var agentIdleTimeout time.Duration
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the unlock agent in the foreground",
+	Long: `Run the unlock agent, which keeps the keys of unlocked vaults in memory so other
+mpass commands do not ask for the master password. The agent listens on a Unix socket
+only accessible to the current user and exits, forgetting all keys, once it has not been
+used for the idle timeout or when 'mpass lock' is run.
+
+'mpass unlock' starts the agent in the background when it is not running yet.`,
+	Args: cobra.NoArgs,
+	RunE: runAgent,
+}
+
+// init registers the flags of the agent command.
+func init() {
+	agentCmd.Flags().DurationVar(&agentIdleTimeout, "idle-timeout", agent.DefaultIdleTimeout, "Exit and forget all keys after this long without use (0 to disable)")
+}
+
+// runAgent executes the "agent" command, serving requests until the agent is locked,
+// times out or is interrupted.
+func runAgent(_ *cobra.Command, _ []string) error {
+	socketPath, err := config.AgentSocketPath()
+	if err != nil {
+		return err
+	}
+
+	server, err := agent.Listen(socketPath, agentIdleTimeout)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Close()
+	}()
+
+	fmt.Printf("🔑 mpass agent listening on %s\n", socketPath)
+	return server.Serve()
+}
+
+// agentClient returns a client for the agent socket.
+func agentClient() (*agent.Client, error) {
+	socketPath, err := config.AgentSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	return agent.NewClient(socketPath), nil
+}
+
+// unlockWithAgent unlocks the vault with a key held by the running agent. It reports false
+// when there is no agent, the vault is not unlocked in it, or the key no longer opens the
+// vault (for example after the master password was changed).
+func unlockWithAgent(vault *storage.VaultManager) bool {
+	client, err := agentClient()
+	if err != nil {
+		return false
+	}
+	key, err := client.Key(vault.Path())
+	if err != nil {
+		return false
+	}
+	return vault.UseKey(key) == nil
+}
+
+// ensureAgent returns a client for the running agent, starting one in the background with
+// the given idle timeout if none is running yet.
+func ensureAgent(idleTimeout time.Duration) (*agent.Client, agent.Status, error) {
+	client, err := agentClient()
+	if err != nil {
+		return nil, agent.Status{}, err
+	}
+	status, err := client.Ping()
+	if err == nil {
+		return client, status, nil
+	}
+	if !errors.Is(err, agent.ErrNotRunning) {
+		return nil, agent.Status{}, err
+	}
+
+	if err := proc.StartSelf(nil, "agent", "--idle-timeout", idleTimeout.String()); err != nil {
+		return nil, agent.Status{}, fmt.Errorf("failed to start agent: %w", err)
+	}
+	deadline := time.Now().Add(agentStartTimeout)
+	for {
+		status, err := client.Ping()
+		if err == nil {
+			return client, status, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, agent.Status{}, fmt.Errorf("agent did not start: %w", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mpass/internal/audit"
+	"mpass/internal/config"
+	"mpass/internal/models"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Report weak, reused and stale passwords",
+		Long: `Check every entry for empty, reused and weak passwords, passwords that have not
+been changed for longer than the maximum age, and entries sharing a username and URL.
+The command exits with an error when it finds a problem, so it can gate scripts.`,
+		Args: cobra.NoArgs,
+		RunE: runAudit,
+	}
+	auditJSON     bool
+	auditMaxAge   time.Duration
+	auditMinScore int
+)
+
+func init() {
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "Print the findings as JSON")
+	auditCmd.Flags().DurationVar(&auditMaxAge, "max-age", config.DefaultPasswordMaxAge, "Report passwords unchanged for longer than this (0 disables the check; default from config)")
+	auditCmd.Flags().IntVar(&auditMinScore, "min-score", config.DefaultAuditMinScore, "Report passwords with a strength score below this (0 disables the check; default from config)")
+}
+
+// auditReport is the output of audit --json.
+type auditReport struct {
+	Entries  int             `json:"entries"`
+	Findings []audit.Finding `json:"findings"`
+}
+
+// auditOptions returns the audit thresholds: the --max-age and --min-score flags if they
+// were given, otherwise the passwords settings from the config file.
+func auditOptions(cmd *cobra.Command) (audit.Options, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return audit.Options{}, err
+	}
+	opts := audit.Options{MinScore: cfg.AuditMinScore(), MaxAge: cfg.PasswordMaxAge(), Now: time.Now()}
+	if cmd.Flags().Changed("max-age") {
+		if auditMaxAge < 0 {
+			return audit.Options{}, fmt.Errorf("--max-age cannot be negative")
+		}
+		opts.MaxAge = auditMaxAge
+	}
+	if cmd.Flags().Changed("min-score") {
+		if auditMinScore < 0 || auditMinScore > config.MaxPasswordScore {
+			return audit.Options{}, fmt.Errorf("--min-score must be between 0 and %d", config.MaxPasswordScore)
+		}
+		opts.MinScore = auditMinScore
+	}
+	return opts, nil
+}
+
+// runAudit executes the "audit" command, printing the problems found with the entries as a
+// table or as JSON. It fails when there is at least one finding.
+func runAudit(cmd *cobra.Command, _ []string) error {
+	opts, err := auditOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	vault, masterPassword, err := openVault()
+	if err != nil {
+		return err
+	}
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	findings := audit.Run(entries, opts)
+	if auditJSON {
+		report := auditReport{Entries: len(entries), Findings: findings}
+		if report.Findings == nil {
+			report.Findings = []audit.Finding{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else {
+		printFindings(len(entries), findings)
+	}
+
+	if len(findings) > 0 {
+		// The findings were reported above; the error only sets the exit status.
+		cmd.SilenceUsage = true
+		return fmt.Errorf("audit found %d problems", len(findings))
+	}
+	return nil
+}
+
+// printFindings prints the findings as a table.
+func printFindings(entries int, findings []audit.Finding) {
+	if len(findings) == 0 {
+		fmt.Printf("✅ No problems found in %d entries\n", entries)
+		return
+	}
+
+	fmt.Printf("🔎 Found %d problems in %d entries:\n\n", len(findings), entries)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tID\tENTRY\tDETAIL")
+	for _, f := range findings {
+		id := models.PasswordEntry{ID: f.EntryID}.ShortID()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Kind, id, f.Title, f.Detail)
+	}
+	w.Flush()
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mpass/internal/config"
+	"mpass/internal/proc"
+	"mpass/pkg/clipboard"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	clearAfter       time.Duration
+	clearDelay       time.Duration
+	clipboardBackend string
+)
+
+// clipboardClearCmd is started in the background by copySecret. It reads the digest of the
+// copied value from stdin, so the value never shows up in the process list, waits and then
+// clears the clipboard unless something else has been copied in the meantime.
+var clipboardClearCmd = &cobra.Command{
+	Use:    "clipboard-clear",
+	Short:  "Clear the clipboard after a delay if it still holds a copied secret",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE:   runClipboardClear,
+}
+
+// clipboardServeCmd is started in the background by the X11 and Wayland clipboard backends.
+// It reads the copied value from stdin and owns the clipboard, offering the value marked as
+// sensitive so that clipboard managers leave it out of their history, until something else
+// is copied. The backend waits until it reports that it owns the clipboard, and copies with
+// its clipboard tool instead if it fails.
+var clipboardServeCmd = &cobra.Command{
+	Use:    "clipboard-serve",
+	Short:  "Serve a copied secret on the clipboard marked as sensitive",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE:   runClipboardServe,
+}
+
+// init registers the flags of the clipboard helpers and the global --clipboard-backend flag,
+// and lets the clipboard backends start clipboard-serve.
+func init() {
+	clipboard.StartServer = func(backend, text string) error {
+		return proc.StartSelfAndWait([]byte(text), serveTimeout, "clipboard-serve", "--clipboard-backend", backend)
+	}
+	clipboardClearCmd.Flags().DurationVar(&clearDelay, "after", 0, "How long to wait before clearing the clipboard")
+	rootCmd.PersistentFlags().StringVar(&clipboardBackend, "clipboard-backend", clipboard.Auto,
+		"Clipboard backend to use ("+clipboard.Auto+", "+strings.Join(clipboard.Names(), ", ")+")")
+}
+
+// serveTimeout is how long a copy waits for clipboard-serve to take the clipboard.
+const serveTimeout = 5 * time.Second
+
+// openClipboard returns the clipboard backend selected with --clipboard-backend.
+// Tests replace it with one returning an in-memory clipboard.
+var openClipboard = func() (clipboard.Backend, error) {
+	return clipboard.New(clipboardBackend)
+}
+
+// scheduleClear starts the clipboard-clear helper for the value with the given digest.
+// Tests replace it to check what would be cleared without starting a process.
+var scheduleClear = func(backend clipboard.Backend, digest string, delay time.Duration) error {
+	args := []string{"clipboard-clear", "--after", delay.String(), "--clipboard-backend", backend.Name()}
+	return proc.StartSelf([]byte(digest+"\n"), args...)
+}
+
+// addClearAfterFlag registers --clear-after on a command that copies secrets to the clipboard.
+func addClearAfterFlag(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&clearAfter, "clear-after", config.DefaultClipboardClearAfter, "Clear the clipboard after this long (0 keeps the password; default from config)")
+}
+
+// clipboardClearAfter returns the --clear-after value if it was given, otherwise the
+// clipboard.clear_after setting from the config file.
+func clipboardClearAfter(cmd *cobra.Command) (time.Duration, error) {
+	if cmd.Flags().Changed("clear-after") {
+		if clearAfter < 0 {
+			return 0, fmt.Errorf("--clear-after cannot be negative")
+		}
+		return clearAfter, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return 0, err
+	}
+	return cfg.ClipboardClearAfter(), nil
+}
+
+// copySecret copies a secret to the clipboard and starts a background helper that clears it
+// again after the --clear-after delay. It returns the delay, which is zero when the secret
+// stays on the clipboard. Failing to start the helper is reported but not fatal.
+func copySecret(cmd *cobra.Command, secret string) (time.Duration, error) {
+	delay, err := clipboardClearAfter(cmd)
+	if err != nil {
+		return 0, err
+	}
+
+	backend, err := openClipboard()
+	if err != nil {
+		return 0, err
+	}
+	if err := backend.Write(secret); err != nil {
+		return 0, fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	if delay == 0 {
+		return 0, nil
+	}
+	// The helper only clears a clipboard it can compare against the copied value.
+	if !backend.CanRead() {
+		fmt.Fprintf(os.Stderr, "⚠️  The %s clipboard backend cannot be cleared automatically\n", backend.Name())
+		return 0, nil
+	}
+
+	if err := scheduleClear(backend, clipboard.Digest(secret), delay); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  The clipboard will not be cleared automatically: %v\n", err)
+		return 0, nil
+	}
+	return delay, nil
+}
+
+// printClearNotice tells the user how long a copied secret stays on the clipboard.
+func printClearNotice(delay time.Duration) {
+	if delay > 0 {
+		fmt.Printf("⏱️  Available for %s, then the clipboard is cleared\n", delay)
+	}
+}
+
+// runClipboardClear executes the hidden "clipboard-clear" helper.
+func runClipboardClear(_ *cobra.Command, _ []string) error {
+	digest, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read clipboard digest: %w", err)
+	}
+
+	backend, err := openClipboard()
+	if err != nil {
+		return err
+	}
+	time.Sleep(clearDelay)
+	_, err = clipboard.ClearIfUnchanged(backend, strings.TrimSpace(digest))
+	return err
+}
+
+// runClipboardServe executes the hidden "clipboard-serve" helper.
+func runClipboardServe(_ *cobra.Command, _ []string) error {
+	text, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		err = fmt.Errorf("failed to read clipboard contents: %w", err)
+		proc.Report(err)
+		return err
+	}
+	err = clipboard.Serve(clipboardBackend, string(text), func() { proc.Report(nil) })
+	proc.Report(err) // no-op once the clipboard was taken
+	return err
+}
@@ -3,39 +3,34 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
-	"mpass/internal/storage"
-	"mpass/internal/ui"
 )
 
-var deleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete a password entry",
-	Long:  "Delete a password entry from the vault by selecting it from a list.",
-	RunE:  runDelete,
-}
-
-func runDelete(cmd *cobra.Command, args []string) error {
-	masterPassword, err := ui.PromptPassword("Enter master password:")
-	if err != nil {
-		return fmt.Errorf("failed to get master password: %w", err)
+var (
+	deleteCmd = &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a password entry",
+		Long:  "Delete a password entry from the vault by ID or by selecting it from a list.",
+		RunE:  runDelete,
 	}
+	deleteID string
+)
 
-	vaultManager := storage.NewVault()
+func init() {
+	deleteCmd.Flags().StringVar(&deleteID, "id", "", "Delete the entry with this ID (or unique ID prefix)")
+}
 
-	entries, err := vaultManager.GetAllEntries(masterPassword)
+func runDelete(cmd *cobra.Command, args []string) error {
+	vaultManager, masterPassword, err := openVault()
 	if err != nil {
-		return fmt.Errorf("failed to load entries: %w", err)
-	}
-	if len(entries) == 0 {
-		return fmt.Errorf("no entries found in vault")
+		return err
 	}
 
-	selectedEntry, err := ui.SelectEntry(entries)
+	selectedEntry, err := selectEntry(vaultManager, deleteID, masterPassword)
 	if err != nil {
-		return fmt.Errorf("failed to select entry: %w", err)
+		return err
 	}
 
-	if err := vaultManager.DeleteEntry(selectedEntry, masterPassword); err != nil {
+	if err := vaultManager.DeleteEntry(selectedEntry.ID, masterPassword); err != nil {
 		return fmt.Errorf("failed to delete entry: %w", err)
 	}
 
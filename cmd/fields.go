@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"mpass/internal/models"
+	"mpass/internal/ui"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags that edit the notes and custom fields of an entry, shared by add and update.
+var (
+	entryNotes     string
+	entryNotesFile string
+	visibleFields  []string
+	secretFields   []string
+	fieldFiles     []string
+	secretFiles    []string
+	removedFields  []string
+)
+
+// addFieldFlags registers the flags that set notes, custom fields and the TOTP secret on
+// cmd, and with removable also the flags that delete them.
+func addFieldFlags(cmd *cobra.Command, removable bool) {
+	cmd.Flags().StringVar(&entryNotes, "notes", "", "Set the notes of the entry")
+	cmd.Flags().StringVar(&entryNotesFile, "notes-file", "", "Set the notes of the entry to the contents of this file")
+	cmd.Flags().StringArrayVar(&visibleFields, "field", nil, "Set a custom field shown by get, as name=value (repeatable)")
+	cmd.Flags().StringArrayVar(&secretFields, "secret-field", nil, "Set a concealed custom field, as name=value or name to be prompted for the value (repeatable)")
+	cmd.Flags().StringArrayVar(&fieldFiles, "field-file", nil, "Set a custom field shown by get to the contents of a file, as name=path or name=- for stdin (repeatable)")
+	cmd.Flags().StringArrayVar(&secretFiles, "secret-field-file", nil, "Set a concealed custom field to the contents of a file, as name=path or name=- for stdin (repeatable)")
+	if removable {
+		cmd.Flags().StringArrayVar(&removedFields, "remove-field", nil, "Remove the custom field with this name (repeatable)")
+	}
+	addOTPFlags(cmd, removable)
+}
+
+// fieldFlagsChanged reports whether any of the flags registered by addFieldFlags was given.
+func fieldFlagsChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{"notes", "notes-file", "field", "secret-field", "field-file",
+		"secret-field-file", "remove-field",
+		"otp", "otp-algorithm", "otp-digits", "otp-period", "remove-otp"} {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFieldFlags edits the notes, custom fields and TOTP secret of entry as requested by
+// the flags registered with addFieldFlags. Concealed fields given without a value are
+// prompted for, and fields given as files are read with readFieldFile.
+func applyFieldFlags(cmd *cobra.Command, entry *models.PasswordEntry) error {
+	if cmd.Flags().Changed("notes") && cmd.Flags().Changed("notes-file") {
+		return fmt.Errorf("--notes cannot be combined with --notes-file")
+	}
+	if cmd.Flags().Changed("notes") {
+		entry.Notes = entryNotes
+	}
+	if entryNotesFile != "" {
+		data, err := os.ReadFile(entryNotesFile)
+		if err != nil {
+			return fmt.Errorf("failed to read notes: %w", err)
+		}
+		entry.Notes = strings.TrimRight(string(data), "\r\n")
+	}
+
+	for _, name := range removedFields {
+		if !entry.RemoveField(name) {
+			return fmt.Errorf("entry has no field named %q", name)
+		}
+	}
+	for _, arg := range visibleFields {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid --field %q, expected name=value", arg)
+		}
+		if err := setField(entry, name, value, false); err != nil {
+			return err
+		}
+	}
+	for _, arg := range secretFields {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			var err error
+			value, err = ui.PromptPassword(fmt.Sprintf("Value of %s:", name))
+			if err != nil {
+				return fmt.Errorf("failed to get value of field %s: %w", name, err)
+			}
+		}
+		if err := setField(entry, name, value, true); err != nil {
+			return err
+		}
+	}
+	stdinUsed := false
+	for _, files := range []struct {
+		flag      string
+		args      []string
+		concealed bool
+	}{{"field-file", fieldFiles, false}, {"secret-field-file", secretFiles, true}} {
+		for _, arg := range files.args {
+			name, path, ok := strings.Cut(arg, "=")
+			if !ok || path == "" {
+				return fmt.Errorf("invalid --%s %q, expected name=path", files.flag, arg)
+			}
+			if path == "-" {
+				if stdinUsed {
+					return fmt.Errorf("only one field can be read from stdin")
+				}
+				stdinUsed = true
+			}
+			value, err := readFieldFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read field %s: %w", name, err)
+			}
+			if err := setField(entry, name, value, files.concealed); err != nil {
+				return err
+			}
+		}
+	}
+	return applyOTPFlags(cmd, entry)
+}
+
+// readFieldFile returns the contents of the file at path, or of stdin if path is "-", as a
+// field value in the form fieldText gives it.
+func readFieldFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fieldText(string(data)), nil
+}
+
+// fieldText tidies up a field value read from a file or a multi-line prompt: a single line
+// loses its line break, while text spanning several lines keeps exactly one at the end, which
+// files such as private keys need to stay valid.
+func fieldText(s string) string {
+	s = strings.TrimRight(s, "\r\n")
+	if strings.Contains(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
+
+// setField validates the field name and stores the field on entry. Fields defined by the
+// entry's type keep the name and visibility the type gives them.
+func setField(entry *models.PasswordEntry, name, value string, concealed bool) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("custom field names cannot be empty")
+	}
+	if spec, ok := entry.Kind().Schema().FieldSpec(name); ok {
+		name, concealed = spec.Name, spec.Concealed
+	}
+	entry.SetField(models.CustomField{Name: name, Value: value, Concealed: concealed})
+	return nil
+}
+
+// printEntryDetails prints the details of entry other than its password: for entries that
+// are not logins the type and the username and URL under the type's labels, then the custom
+// fields, whether it has a TOTP secret and the notes. Concealed fields are listed by name
+// only, and the notes are left out when they are the secret of a secure note.
+func printEntryDetails(entry models.PasswordEntry) {
+	schema := entry.Kind().Schema()
+	if entry.Kind() != models.TypeLogin {
+		fmt.Printf("%s %s: %s\n", typeIcon(entry.Kind()), upperFirst(schema.Label), entry.Title())
+		if schema.Username != "" && entry.Username != "" {
+			fmt.Printf("   %s: %s\n", schema.Username, entry.Username)
+		}
+		if schema.URL != "" && entry.URL != "" {
+			fmt.Printf("   %s: %s\n", schema.URL, entry.URL)
+		}
+	}
+	if len(entry.Fields) > 0 {
+		fmt.Println("🏷️  Fields:")
+		for _, field := range entry.Fields {
+			if field.Concealed {
+				fmt.Printf("   %s: ******** (copy with --field %q)\n", field.Name, field.Name)
+			} else {
+				fmt.Printf("   %s: %s\n", field.Name, field.Value)
+			}
+		}
+	}
+	if entry.OTP != nil {
+		fmt.Printf("🔢 One-time codes: run 'mpass otp %s'\n", entry.ShortID())
+	}
+	if entry.Notes != "" && schema.Copy != models.CopyNotes {
+		fmt.Println("📝 Notes:")
+		for _, line := range strings.Split(entry.Notes, "\n") {
+			fmt.Println("   " + line)
+		}
+	}
+}
+
+// typeIcon returns the icon shown in front of entries of type t.
+func typeIcon(t models.EntryType) string {
+	switch t {
+	case models.TypeNote:
+		return "📝"
+	case models.TypeCard:
+		return "💳"
+	case models.TypeIdentity:
+		return "🪪"
+	case models.TypeAPIToken:
+		return "🎟️"
+	case models.TypeSSHKey:
+		return "🔐"
+	default:
+		return "🔑"
+	}
+}
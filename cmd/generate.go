@@ -1,60 +1,147 @@
 package cmd
 
 import (
-	"crypto/rand"
 	"fmt"
-	"math/big"
-	"mpass/pkg/clipboard"
+	"mpass/internal/generator"
+	"mpass/internal/passphrase"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	length      int
-	charset     string
+	policy      generator.Policy
+	preset      string
+	words       passphrase.Options
 	generateCmd = &cobra.Command{
 		Use:   "generate",
-		Short: "Generate a random password",
-		Long:  "Generate a secure random password with specified length and character set",
-		RunE:  runGenerate,
+		Short: "Generate a random password or passphrase",
+		Long: `Generate a secure random password with specified length and character set, or
+with --words a diceware passphrase from the EFF large wordlist.`,
+		RunE: runGenerate,
 	}
 )
 
 func init() {
-	generateCmd.Flags().IntVarP(&length, "length", "n", 16, "Length of the password to generate")
-	generateCmd.Flags().StringVarP(&charset, "charset", "c", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()", "Character set to use for password generation")
+	addPolicyFlags(generateCmd)
+	generateCmd.Flags().IntVar(&words.Words, "words", 0, "Generate a passphrase of this many words instead of a password")
+	generateCmd.Flags().StringVar(&words.Separator, "separator", "-", "Separator between the words of a passphrase")
+	generateCmd.Flags().BoolVar(&words.Capitalize, "capitalize", false, "Capitalize every word of a passphrase")
+	generateCmd.Flags().BoolVar(&words.AppendDigit, "append-digit", false, "Append a random digit to a passphrase")
+	addClearAfterFlag(generateCmd)
 }
 
-func runGenerate(_ *cobra.Command, _ []string) error {
-	if length <= 0 {
-		return fmt.Errorf("Length must be greater than zero")
-	}
+// passphraseFlags are the flags that only apply together with --words.
+var passphraseFlags = []string{"separator", "capitalize", "append-digit"}
+
+// addPolicyFlags registers the flags that describe the password to generate on cmd.
+func addPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&policy.Length, "length", "n", 16, "Length of the password to generate")
+	cmd.Flags().StringVarP(&policy.Charset, "charset", "c", generator.DefaultCharset, "Character set to use for password generation")
+	cmd.Flags().StringVar(&preset, "preset", "", "Named character set to use ("+strings.Join(generator.PresetNames(), ", ")+")")
+	cmd.Flags().IntVar(&policy.MinUpper, "min-upper", 0, "Minimum number of upper case letters")
+	cmd.Flags().IntVar(&policy.MinLower, "min-lower", 0, "Minimum number of lower case letters")
+	cmd.Flags().IntVar(&policy.MinDigits, "min-digits", 0, "Minimum number of digits")
+	cmd.Flags().IntVar(&policy.MinSymbols, "min-symbols", 0, "Minimum number of symbols")
+	cmd.Flags().BoolVar(&policy.ExcludeAmbiguous, "exclude-ambiguous", false, "Leave out characters that are easily confused ("+generator.Ambiguous+")")
+	cmd.Flags().StringVar(&policy.Exclude, "exclude", "", "Characters to leave out of the password")
+}
+
+// policyFlags are the flags registered by addPolicyFlags.
+var policyFlags = []string{"length", "charset", "preset", "min-upper", "min-lower", "min-digits", "min-symbols", "exclude-ambiguous", "exclude"}
 
-	if charset == "" {
-		return fmt.Errorf("The character set cannot be empty")
+// changedPolicyFlag returns the name of a policy flag given on the command line, or "".
+func changedPolicyFlag(cmd *cobra.Command) string {
+	for _, name := range policyFlags {
+		if cmd.Flags().Changed(name) {
+			return name
+		}
 	}
+	return ""
+}
 
-	var sb strings.Builder
-	var password string
+// generatePassword generates a password with the policy given on the command line.
+func generatePassword(cmd *cobra.Command) (string, error) {
+	selected, err := selectedPolicy(cmd)
+	if err != nil {
+		return "", err
+	}
+	password, err := generator.Generate(selected)
+	if err != nil {
+		return "", fmt.Errorf("Error generating the password: %w", err)
+	}
+	return password, nil
+}
 
-	for i := 0; i < length; i++ {
-		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+// selectedPolicy returns the policy given with the flags registered by addPolicyFlags,
+// with the character set of --preset if one was named.
+func selectedPolicy(cmd *cobra.Command) (generator.Policy, error) {
+	selected := policy
+	if cmd.Flags().Changed("charset") && policy.Charset == "" {
+		return selected, fmt.Errorf("The character set cannot be empty")
+	}
+	if cmd.Flags().Changed("preset") {
+		if cmd.Flags().Changed("charset") {
+			return selected, fmt.Errorf("--preset cannot be combined with --charset")
+		}
+		charset, err := generator.Preset(preset)
 		if err != nil {
-			return fmt.Errorf("Error generating the password: %w", err)
+			return selected, err
 		}
-		sb.WriteByte(charset[idx.Int64()])
+		selected.Charset = charset
 	}
+	return selected, nil
+}
 
-	password = sb.String()
+func runGenerate(cmd *cobra.Command, _ []string) error {
+	if cmd.Flags().Changed("words") {
+		if name := changedPolicyFlag(cmd); name != "" {
+			return fmt.Errorf("--words cannot be combined with --%s, which only applies to passwords", name)
+		}
+		return generatePassphrase(cmd)
+	}
+	for _, name := range passphraseFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s only applies to passphrases, use it with --words", name)
+		}
+	}
+
+	password, err := generatePassword(cmd)
+	if err != nil {
+		return err
+	}
 
 	fmt.Println("New password generated:", password)
 
-	if err := clipboard.WriteText(password); err != nil {
-		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	delay, err := copySecret(cmd, password)
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("✅ Password copied to clipboard!")
+	printClearNotice(delay)
+
+	return nil
+}
+
+// generatePassphrase generates a diceware passphrase with the --words options, reports its
+// entropy and copies it to the clipboard.
+func generatePassphrase(cmd *cobra.Command) error {
+	phrase, err := passphrase.Generate(words)
+	if err != nil {
+		return fmt.Errorf("Error generating the passphrase: %w", err)
+	}
+
+	fmt.Println("New passphrase generated:", phrase)
+	fmt.Printf("🎲 Entropy: %.2f bits\n", words.Entropy())
+
+	delay, err := copySecret(cmd, phrase)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Passphrase copied to clipboard!")
+	printClearNotice(delay)
 
 	return nil
 }
@@ -3,9 +3,7 @@ package cmd
 import (
 	"fmt"
 	"mpass/internal/models"
-	"mpass/internal/storage"
 	"mpass/internal/ui"
-	"mpass/pkg/clipboard"
 
 	"github.com/spf13/cobra"
 )
@@ -14,41 +12,73 @@ var (
 	getCmd = &cobra.Command{
 		Use:   "get",
 		Short: "Get a password entry",
-		Long:  "Search and retrieve a password entry by username or URL",
+		Long:  "Search and retrieve a password entry by ID, username, URL, name, type, folder or tags",
 		RunE:  runGet,
 	}
 	searchUser string
 	searchURL  string
+	getID      string
+	previous   int
+	getField   string
+	withOTP    bool
 )
 
 // init initializes the flags for the getCmd command.
-// It sets up the command-line options for searching by username or URL.
+// It sets up the command-line options for selecting an entry by ID or searching by username or URL.
 func init() {
 	getCmd.Flags().StringVarP(&searchUser, "user", "u", "", "Search by username")
 	getCmd.Flags().StringVarP(&searchURL, "url", "l", "", "Search by URL")
+	getCmd.Flags().StringVar(&getID, "id", "", "Select the entry with this ID (or unique ID prefix)")
+	getCmd.Flags().IntVar(&previous, "previous", 0, "Copy the password used N changes ago instead of the current one")
+	getCmd.Flags().StringVar(&getField, "field", "", "Copy the custom field with this name instead of the password")
+	getCmd.Flags().BoolVar(&withOTP, "with-otp", false, "After the password, copy the current one-time code when Enter is pressed")
+	addClearAfterFlag(getCmd)
+	addFilterFlags(getCmd)
 }
 
 // runGet executes the logic for the "get" command.
-// It prompts the user for the master password, searches for password entries
-// by username or URL, allows selection if multiple entries are found, and
-// copies the selected password to the clipboard.
-func runGet(_ *cobra.Command, _ []string) error {
-	if searchUser == "" && searchURL == "" {
-		return fmt.Errorf("please provide either --user or --url flag")
-	}
-
-	// Get master password
-	masterPassword, err := ui.PromptPassword("Enter master password:")
+// It prompts the user for the master password, looks up the entry by ID or searches
+// for password entries by username, URL, folder or tags, allows selection if multiple entries are
+// found, and copies the selected password (or the secret of other entry types, with --previous
+// an older password, or with --field a custom field) to the clipboard until the --clear-after
+// delay. With --with-otp the current one-time code is copied next.
+func runGet(cmd *cobra.Command, _ []string) error {
+	filter, err := entryFilter()
 	if err != nil {
-		return fmt.Errorf("failed to get master password: %w", err)
+		return err
+	}
+	if getID == "" && searchUser == "" && searchURL == "" && !filter.IsSet() {
+		return fmt.Errorf("please provide either --id, --user, --url, --name, --type, --folder or --tag flag")
+	}
+	if cmd.Flags().Changed("previous") && previous < 1 {
+		return fmt.Errorf("--previous must be at least 1")
+	}
+	if previous > 0 && getField != "" {
+		return fmt.Errorf("--previous cannot be combined with --field")
+	}
+	if withOTP && (previous > 0 || getField != "") {
+		return fmt.Errorf("--with-otp cannot be combined with --previous or --field")
 	}
 
 	// Load vault
-	vault := storage.NewVault()
-	entries, err := vault.SearchEntries(searchUser, searchURL, masterPassword)
+	vault, masterPassword, err := openVault()
 	if err != nil {
-		return fmt.Errorf("failed to search entries: %w", err)
+		return err
 	}
+	var entries []models.PasswordEntry
+	if getID != "" {
+		entry, err := vault.GetEntry(getID, masterPassword)
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+		entries = []models.PasswordEntry{*entry}
+	} else {
+		entries, err = vault.SearchEntries(searchUser, searchURL, masterPassword)
+		if err != nil {
+			return fmt.Errorf("failed to search entries: %w", err)
+		}
+	}
+	entries = filter.Apply(entries)
 
 	if len(entries) == 0 {
 		fmt.Println("❌ No matching entries found")
@@ -67,12 +97,85 @@ func runGet(_ *cobra.Command, _ []string) error {
 		selectedEntry = selected
 	}
 
-	// Copy password to clipboard
-	if err := clipboard.WriteText(selectedEntry.Password); err != nil {
-		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	if withOTP && selectedEntry.OTP == nil {
+		return fmt.Errorf("entry %s has no TOTP secret", selectedEntry.Title())
+	}
+	if previous > 0 {
+		return copyPrevious(cmd, selectedEntry)
+	}
+	if getField != "" {
+		return copyField(cmd, selectedEntry)
+	}
+
+	// Copy the secret of the entry's type to clipboard
+	label, secret := entrySecret(*selectedEntry)
+	if secret != "" {
+		delay, err := copySecret(cmd, secret)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s for %s copied to clipboard!\n", label, selectedEntry.Title())
+		printClearNotice(delay)
+	}
+	printEntryDetails(*selectedEntry)
+
+	if withOTP {
+		if secret != "" {
+			if err := waitForCode(); err != nil {
+				return fmt.Errorf("failed to wait for confirmation: %w", err)
+			}
+		}
+		return copyCode(cmd, selectedEntry)
+	}
+	return nil
+}
+
+// entrySecret returns what get copies for entry, as set by its type's schema, with a label
+// for messages. The secret is empty if the type has none or the entry does not fill it in.
+func entrySecret(entry models.PasswordEntry) (string, string) {
+	schema := entry.Kind().Schema()
+	switch schema.Copy {
+	case "":
+		return "", ""
+	case models.CopyPassword:
+		return schema.Password, entry.Password
+	case models.CopyNotes:
+		return "Note", entry.Notes
+	default:
+		field, _ := entry.Field(schema.Copy)
+		return schema.Copy, field.Value
+	}
+}
+
+// copyField copies the custom field named by --field to the clipboard.
+func copyField(cmd *cobra.Command, entry *models.PasswordEntry) error {
+	field, ok := entry.Field(getField)
+	if !ok {
+		return fmt.Errorf("entry %s has no field named %q", entry.Title(), getField)
+	}
+	delay, err := copySecret(cmd, field.Value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Field %s of %s copied to clipboard!\n", field.Name, entry.Title())
+	printClearNotice(delay)
+	return nil
+}
+
+// copyPrevious copies the password the entry used --previous changes ago to the clipboard.
+func copyPrevious(cmd *cobra.Command, entry *models.PasswordEntry) error {
+	old, err := entry.Previous(previous)
+	if err != nil {
+		return err
+	}
+	delay, err := copySecret(cmd, old.Password)
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("✅ Password for %s@%s copied to clipboard!\n",
-		selectedEntry.Username, selectedEntry.URL)
+	fmt.Printf("✅ Previous password %d for %s (replaced %s) copied to clipboard!\n",
+		previous, entry.Title(), old.ReplacedAt.Format("2006-01-02 15:04:05"))
+	printClearNotice(delay)
 	return nil
 }
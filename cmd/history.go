@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [id]",
+	Short: "List the previous passwords of an entry",
+	Long: `List when the password of an entry was changed. The entry is given by ID (or
+unique ID prefix) or selected from a list. Passwords are not shown; copy an older one
+with 'mpass get --id <id> --previous N'.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistory,
+}
+
+// runHistory executes the "history" command, printing when the current password was set
+// and when each previous password was replaced.
+func runHistory(_ *cobra.Command, args []string) error {
+	id := ""
+	if len(args) == 1 {
+		id = args[0]
+	}
+
+	vault, masterPassword, err := openVault()
+	if err != nil {
+		return err
+	}
+	entry, err := selectEntry(vault, id, masterPassword)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🕘 Password history for %s  [%s]:\n\n", entry.Title(), entry.ShortID())
+	fmt.Printf("current  set %s\n", entry.PasswordSetAt().Format("2006-01-02 15:04:05"))
+	for i, previous := range entry.History {
+		fmt.Printf("%-7d  replaced %s\n", i+1, previous.ReplacedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	if len(entry.History) == 0 {
+		fmt.Println("\nThe password has not been changed yet.")
+		return nil
+	}
+	fmt.Printf("\nRun 'mpass get --id %s --previous N' to copy one of them.\n", entry.ShortID())
+	return nil
+}
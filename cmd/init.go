@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/storage"
+	"mpass/internal/strength"
+	"mpass/internal/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a new vault",
+	Long: `Create a new, empty vault protected by a master password. The password is
+asked for twice so that a typo cannot become the vault's key.`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
+}
+
+// runInit executes the "init" command. It asks twice for the new master password,
+// shows a strength estimate, asks for confirmation if the password is weak, and
+// writes the new vault. When a password source is configured, the password is taken
+// from it as is, so vaults can be created from scripts.
+func runInit(_ *cobra.Command, _ []string) error {
+	vault, err := newVaultManager()
+	if err != nil {
+		return err
+	}
+	if vault.Exists() {
+		return fmt.Errorf("a vault already exists at %s", vault.Path())
+	}
+
+	if passwordSource.IsSet() {
+		masterPassword, err := passwordSource.Read()
+		if err != nil {
+			return fmt.Errorf("failed to get master password: %w", err)
+		}
+		fmt.Printf("🔎 Strength: %s\n", strength.Estimate(masterPassword))
+		return createVault(vault, masterPassword)
+	}
+	if !ui.IsInteractive() {
+		return ui.ErrNotInteractive
+	}
+
+	masterPassword, err := ui.PromptPassword("Choose a master password:")
+	if err != nil {
+		return fmt.Errorf("failed to get master password: %w", err)
+	}
+	if masterPassword == "" {
+		return fmt.Errorf("master password cannot be empty")
+	}
+
+	result := strength.Estimate(masterPassword)
+	fmt.Printf("🔎 Strength: %s\n", result)
+	if result.Score < 3 {
+		confirmed, err := ui.PromptConfirm("This master password is easy to guess. Use it anyway?")
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Vault not created.")
+			return nil
+		}
+	}
+
+	confirmPassword, err := ui.PromptPassword("Confirm master password:")
+	if err != nil {
+		return fmt.Errorf("failed to get master password: %w", err)
+	}
+	if masterPassword != confirmPassword {
+		return fmt.Errorf("master passwords do not match")
+	}
+
+	return createVault(vault, masterPassword)
+}
+
+// createVault writes a new vault protected by masterPassword and reports where it was created.
+func createVault(vault *storage.VaultManager, masterPassword string) error {
+	if err := vault.Create(masterPassword); err != nil {
+		return fmt.Errorf("failed to create vault: %w", err)
+	}
+
+	fmt.Printf("✅ Vault created at %s\n", vault.Path())
+	return nil
+}
@@ -2,8 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"mpass/internal/storage"
-	"mpass/internal/ui"
 
 	"github.com/spf13/cobra"
 )
@@ -11,26 +9,34 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all password entries",
-	Long:  "Display all stored password entries (passwords are hidden)",
+	Long:  "Display all stored password entries (passwords are hidden), optionally only those of a type, in a folder or with given tags",
 	RunE:  runList,
 }
 
+func init() {
+	addFilterFlags(listCmd)
+}
+
 // runList executes the "list" command, prompting the user for the master password,
-// loading all password entries from the vault, and displaying them (without showing passwords).
+// loading the password entries from the vault that match the filter flags, and displaying
+// them with their type, folder and tags (without showing passwords).
 // Returns an error if the master password is not provided or if entries cannot be loaded.
 func runList(_ *cobra.Command, _ []string) error {
-	// Get master password
-	masterPassword, err := ui.PromptPassword("Enter master password:")
+	filter, err := entryFilter()
 	if err != nil {
-		return fmt.Errorf("failed to get master password: %w", err)
+		return err
 	}
 
 	// Load vault
-	vault := storage.NewVault()
+	vault, masterPassword, err := openVault()
+	if err != nil {
+		return err
+	}
 	entries, err := vault.GetAllEntries(masterPassword)
 	if err != nil {
 		return fmt.Errorf("failed to load entries: %w", err)
 	}
+	entries = filter.Apply(entries)
 
 	if len(entries) == 0 {
 		fmt.Println("📭 No password entries found")
@@ -39,7 +45,7 @@ func runList(_ *cobra.Command, _ []string) error {
 
 	fmt.Printf("📚 Found %d password entries:\n\n", len(entries))
 	for i, entry := range entries {
-		fmt.Printf("%d. %s@%s\n", i+1, entry.Username, entry.URL)
+		fmt.Printf("%d. %s  [%s]%s\n", i+1, entry.Title(), entry.ShortID(), entryLabels(entry))
 	}
 
 	return nil
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mpass/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Lock all vaults and stop the agent",
+	Long:  "Make the unlock agent forget the keys of all unlocked vaults and exit",
+	Args:  cobra.NoArgs,
+	RunE:  runLock,
+}
+
+// runLock executes the "lock" command. It is not an error if no agent is running.
+func runLock(_ *cobra.Command, _ []string) error {
+	client, err := agentClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Lock(); err != nil {
+		if errors.Is(err, agent.ErrNotRunning) {
+			fmt.Println("🔒 No agent running, nothing to lock")
+			return nil
+		}
+		return fmt.Errorf("failed to lock agent: %w", err)
+	}
+
+	fmt.Println("🔒 Vaults locked")
+	return nil
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/models"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagCmd = &cobra.Command{
+		Use:   "tag",
+		Short: "Add or remove tags on an entry",
+	}
+	tagAddCmd = &cobra.Command{
+		Use:   "add <id> <tag>...",
+		Short: "Add tags to an entry",
+		Args:  cobra.MinimumNArgs(2),
+		RunE:  runTagAdd,
+	}
+	tagRmCmd = &cobra.Command{
+		Use:   "rm <id> <tag>...",
+		Short: "Remove tags from an entry",
+		Args:  cobra.MinimumNArgs(2),
+		RunE:  runTagRm,
+	}
+	mvCmd = &cobra.Command{
+		Use:   "mv [id] <folder>",
+		Short: "Move entries to another folder",
+		Long: `Move the entry with the given ID (or unique ID prefix) to a folder such as
+work/aws/prod; "/" is the top level. With --folder, --tag, --type or --name, move every
+matching entry instead. With --folder the subfolders below the --folder path are kept;
+otherwise every matching entry ends up directly in the destination folder.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: runMv,
+	}
+	filterTags   []string
+	filterFolder string
+	filterType   string
+	filterName   string
+)
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRmCmd)
+	addFilterFlags(mvCmd)
+}
+
+// addFilterFlags registers --tag, --folder, --type and --name, which restrict cmd to
+// matching entries.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&filterTags, "tag", nil, "Only entries with this tag (repeatable, all must match)")
+	cmd.Flags().StringVar(&filterFolder, "folder", "", "Only entries in this folder or its subfolders")
+	cmd.Flags().StringVar(&filterType, "type", "", "Only entries of this type: "+typeNames())
+	cmd.Flags().StringVar(&filterName, "name", "", "Only entries whose name contains this")
+}
+
+// typeNames lists the entry types for flag help.
+func typeNames() string {
+	names := make([]string, 0, len(models.Types()))
+	for _, t := range models.Types() {
+		names = append(names, string(t))
+	}
+	return strings.Join(names, ", ")
+}
+
+// addPlacementFlags registers --tag and --folder on a command that creates an entry.
+func addPlacementFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&filterTags, "tag", nil, "Tag the entry (repeatable)")
+	cmd.Flags().StringVar(&filterFolder, "folder", "", "Put the entry in this folder, such as work/aws")
+}
+
+// entryFilter returns the filter given with --folder, --tag, --type and --name, cleaned up.
+func entryFilter() (models.Filter, error) {
+	folder, err := models.CleanFolder(filterFolder)
+	if err != nil {
+		return models.Filter{}, err
+	}
+	filter := models.Filter{Folder: folder, Name: filterName}
+	if filterType != "" {
+		if filter.Type, err = models.ParseType(filterType); err != nil {
+			return models.Filter{}, err
+		}
+	}
+	for _, tag := range filterTags {
+		tag, err := models.CleanTag(tag)
+		if err != nil {
+			return models.Filter{}, err
+		}
+		filter.Tags = append(filter.Tags, tag)
+	}
+	return filter, nil
+}
+
+// folderName returns folder for messages, naming the top level.
+func folderName(folder string) string {
+	if folder == "" {
+		return "the top level"
+	}
+	return folder
+}
+
+// entryLabels returns the type (unless a login), folder and tags of entry for listings, or
+// "" if there is nothing to show.
+func entryLabels(entry models.PasswordEntry) string {
+	var labels []string
+	if entry.Kind() != models.TypeLogin {
+		labels = append(labels, "("+entry.Kind().Schema().Label+")")
+	}
+	if entry.Folder != "" {
+		labels = append(labels, "📁 "+entry.Folder)
+	}
+	for _, tag := range entry.Tags {
+		labels = append(labels, "#"+tag)
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "  " + strings.Join(labels, " ")
+}
+
+// runTagAdd executes the "tag add" command.
+func runTagAdd(_ *cobra.Command, args []string) error {
+	return editTags(args[0], args[1:], true)
+}
+
+// runTagRm executes the "tag rm" command.
+func runTagRm(_ *cobra.Command, args []string) error {
+	return editTags(args[0], args[1:], false)
+}
+
+// editTags adds tags to or removes them from the entry with the given ID and saves it.
+// Removing a tag the entry does not have is an error.
+func editTags(id string, tags []string, add bool) error {
+	vault, masterPassword, err := openVault()
+	if err != nil {
+		return err
+	}
+	entry, err := selectEntry(vault, id, masterPassword)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		tag, err := models.CleanTag(tag)
+		if err != nil {
+			return err
+		}
+		if add {
+			entry.AddTag(tag)
+		} else if !entry.RemoveTag(tag) {
+			return fmt.Errorf("entry %s has no tag %q", entry.Title(), tag)
+		}
+	}
+	if err := vault.UpdateEntry(*entry, masterPassword); err != nil {
+		return fmt.Errorf("failed to save updated entry: %w", err)
+	}
+
+	tagList := "no tags"
+	if len(entry.Tags) > 0 {
+		tagList = "#" + strings.Join(entry.Tags, " #")
+	}
+	fmt.Printf("✅ Tags of %s: %s\n", entry.Title(), tagList)
+	return nil
+}
+
+// runMv executes the "mv" command, moving a single entry or, with a filter flag, all
+// matching entries to the destination folder.
+func runMv(_ *cobra.Command, args []string) error {
+	filter, err := entryFilter()
+	if err != nil {
+		return err
+	}
+	bulk := filter.IsSet()
+	if bulk && len(args) != 1 {
+		return fmt.Errorf("with --folder, --tag, --type or --name, give only the destination folder")
+	}
+	if !bulk && len(args) != 2 {
+		return fmt.Errorf("please provide the entry ID and the destination folder")
+	}
+	dest, err := models.CleanFolder(args[len(args)-1])
+	if err != nil {
+		return err
+	}
+
+	vault, masterPassword, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	if !bulk {
+		entry, err := selectEntry(vault, args[0], masterPassword)
+		if err != nil {
+			return err
+		}
+		entry.Folder = dest
+		if err := vault.UpdateEntry(*entry, masterPassword); err != nil {
+			return fmt.Errorf("failed to save updated entry: %w", err)
+		}
+		fmt.Printf("✅ Moved %s to %s\n", entry.Title(), folderName(dest))
+		return nil
+	}
+
+	all, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+	entries := filter.Apply(all)
+	if len(entries) == 0 {
+		fmt.Println("❌ No matching entries found")
+		return nil
+	}
+	for i := range entries {
+		if filter.Folder == "" {
+			entries[i].Folder = dest
+			continue
+		}
+		// Keep the part of the path below the folder being moved
+		rest := strings.TrimPrefix(strings.TrimPrefix(entries[i].Folder, filter.Folder), "/")
+		entries[i].Folder = strings.Trim(dest+"/"+rest, "/")
+	}
+	if err := vault.UpdateEntries(entries, masterPassword); err != nil {
+		return fmt.Errorf("failed to save updated entries: %w", err)
+	}
+	fmt.Printf("✅ Moved %d entries to %s\n", len(entries), folderName(dest))
+	return nil
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/models"
+	"mpass/internal/totp"
+	"mpass/internal/ui"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var otpCmd = &cobra.Command{
+	Use:   "otp [query]",
+	Short: "Copy the current one-time code of an entry",
+	Long: `Compute the current TOTP code (RFC 6238) of an entry and copy it to the clipboard.
+The query is an ID prefix or text found in the name, username or URL of the entry; only
+entries with a TOTP secret are searched, and a list is shown when several match. Store a
+secret with 'mpass add --otp' or 'mpass update --otp'.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOTP,
+}
+
+// Flags that set the TOTP secret of an entry, shared by add and update.
+var (
+	otpSecret    string
+	otpAlgorithm string
+	otpDigits    int
+	otpPeriod    int
+	removeOTP    bool
+)
+
+// now returns the time codes are computed for; tests replace it.
+var now = time.Now
+
+// waitForCode blocks until the user asks for the one-time code after get --with-otp
+// copied the password; tests replace it.
+var waitForCode = func() error {
+	_, err := ui.PromptInput("Press Enter to copy the one-time code...")
+	return err
+}
+
+func init() {
+	addClearAfterFlag(otpCmd)
+	addFilterFlags(otpCmd)
+}
+
+// addOTPFlags registers the flags that set the TOTP secret of an entry on cmd, and with
+// removable also the flag that deletes it.
+func addOTPFlags(cmd *cobra.Command, removable bool) {
+	cmd.Flags().StringVar(&otpSecret, "otp", "", "Set the TOTP secret, as an otpauth:// URI or base32 secret, or - to be prompted for it")
+	cmd.Flags().StringVar(&otpAlgorithm, "otp-algorithm", "", "Set the TOTP algorithm: SHA1, SHA256 or SHA512")
+	cmd.Flags().IntVar(&otpDigits, "otp-digits", 0, "Set the number of digits of the TOTP codes")
+	cmd.Flags().IntVar(&otpPeriod, "otp-period", 0, "Set the seconds each TOTP code is valid")
+	if removable {
+		cmd.Flags().BoolVar(&removeOTP, "remove-otp", false, "Remove the TOTP secret")
+	}
+}
+
+// applyOTPFlags sets or removes the TOTP secret of entry as requested by the flags
+// registered with addOTPFlags. The parameter flags override those of an otpauth URI and
+// can also change the parameters of the secret already stored.
+func applyOTPFlags(cmd *cobra.Command, entry *models.PasswordEntry) error {
+	paramsChanged := cmd.Flags().Changed("otp-algorithm") || cmd.Flags().Changed("otp-digits") || cmd.Flags().Changed("otp-period")
+	if removeOTP {
+		if cmd.Flags().Changed("otp") || paramsChanged {
+			return fmt.Errorf("--remove-otp cannot be combined with other --otp flags")
+		}
+		if entry.OTP == nil {
+			return fmt.Errorf("entry %s has no TOTP secret", entry.Title())
+		}
+		entry.OTP = nil
+		return nil
+	}
+
+	key := entry.OTP
+	if cmd.Flags().Changed("otp") {
+		secret := otpSecret
+		if secret == "-" {
+			var err error
+			secret, err = ui.PromptPassword("TOTP secret or otpauth URI:")
+			if err != nil {
+				return fmt.Errorf("failed to get TOTP secret: %w", err)
+			}
+		}
+		parsed, err := totp.Parse(secret)
+		if err != nil {
+			return err
+		}
+		key = parsed
+	}
+	if !paramsChanged {
+		entry.OTP = key
+		return nil
+	}
+	if key == nil {
+		return fmt.Errorf("entry %s has no TOTP secret, set one with --otp", entry.Title())
+	}
+
+	updated := *key
+	if cmd.Flags().Changed("otp-algorithm") {
+		updated.Algorithm = otpAlgorithm
+	}
+	if cmd.Flags().Changed("otp-digits") {
+		if otpDigits <= 0 {
+			return fmt.Errorf("--otp-digits must be positive")
+		}
+		updated.Digits = otpDigits
+	}
+	if cmd.Flags().Changed("otp-period") {
+		if otpPeriod <= 0 {
+			return fmt.Errorf("--otp-period must be positive")
+		}
+		updated.Period = otpPeriod
+	}
+	if err := updated.Normalize(); err != nil {
+		return err
+	}
+	entry.OTP = &updated
+	return nil
+}
+
+// runOTP executes the "otp" command, copying the current code of the entry matching the
+// query and the filter flags.
+func runOTP(cmd *cobra.Command, args []string) error {
+	filter, err := entryFilter()
+	if err != nil {
+		return err
+	}
+	query := ""
+	if len(args) == 1 {
+		query = args[0]
+	}
+
+	vault, masterPassword, err := openVault()
+	if err != nil {
+		return err
+	}
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		return fmt.Errorf("failed to load entries: %w", err)
+	}
+
+	var matches []models.PasswordEntry
+	for _, entry := range filter.Apply(entries) {
+		if entry.OTP != nil && matchesQuery(entry, query) {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no entry with a TOTP secret matches %q", query)
+	}
+
+	entry := &matches[0]
+	if len(matches) > 1 {
+		entry, err = ui.SelectEntry(matches)
+		if err != nil {
+			return fmt.Errorf("failed to select entry: %w", err)
+		}
+	}
+	return copyCode(cmd, entry)
+}
+
+// matchesQuery reports whether entry has an ID starting with query or a name, username or
+// URL containing it, ignoring case. An empty query matches every entry.
+func matchesQuery(entry models.PasswordEntry, query string) bool {
+	query = strings.ToLower(query)
+	if strings.HasPrefix(strings.ToLower(entry.ID), query) {
+		return true
+	}
+	for _, value := range []string{entry.Name, entry.Username, entry.URL} {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyCode copies the current one-time code of entry to the clipboard and prints how long
+// it stays valid.
+func copyCode(cmd *cobra.Command, entry *models.PasswordEntry) error {
+	if entry.OTP == nil {
+		return fmt.Errorf("entry %s has no TOTP secret", entry.Title())
+	}
+	at := now()
+	code, err := entry.OTP.Code(at)
+	if err != nil {
+		return fmt.Errorf("failed to compute one-time code: %w", err)
+	}
+	delay, err := copySecret(cmd, code)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ One-time code for %s copied to clipboard!\n", entry.Title())
+	fmt.Printf("⏳ Valid for another %ds\n", (entry.OTP.Remaining(at)+time.Second-1)/time.Second)
+	printClearNotice(delay)
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/storage"
+	"mpass/internal/ui"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the master password",
+	Long:  "Change the master password and re-encrypt the vault with a fresh salt",
+	RunE:  runPasswd,
+}
+
+// runPasswd executes the "passwd" command. It unlocks the vault with the current master
+// password, asks twice for the new one, and re-encrypts the vault with a new salt.
+// The old vault stays in place until the re-encrypted one has been written and verified.
+// The current password may come from a password source; the new one is always typed.
+func runPasswd(_ *cobra.Command, _ []string) error {
+	vault, err := newVaultManager()
+	if err != nil {
+		return err
+	}
+	if !vault.Exists() {
+		return storage.ErrVaultNotFound
+	}
+
+	oldPassword, err := ui.ReadMasterPassword("Enter current master password:", passwordSource)
+	if err != nil {
+		return fmt.Errorf("failed to get master password: %w", err)
+	}
+
+	newPassword, err := ui.PromptPassword("Enter new master password:")
+	if err != nil {
+		return fmt.Errorf("failed to get new master password: %w", err)
+	}
+	if newPassword == "" {
+		return fmt.Errorf("new master password cannot be empty")
+	}
+
+	confirmPassword, err := ui.PromptPassword("Confirm new master password:")
+	if err != nil {
+		return fmt.Errorf("failed to get new master password: %w", err)
+	}
+	if newPassword != confirmPassword {
+		return fmt.Errorf("new master passwords do not match")
+	}
+
+	// Unlocking first derives the current key once and makes the manager keep the new one
+	if _, err := vault.Unlock(oldPassword); err != nil {
+		return fmt.Errorf("failed to unlock vault: %w", err)
+	}
+	if err := vault.ChangeMasterPassword(oldPassword, newPassword); err != nil {
+		return fmt.Errorf("failed to change master password: %w", err)
+	}
+
+	fmt.Println("✅ Master password changed successfully!")
+	refreshAgentKey(vault)
+	return nil
+}
+
+// refreshAgentKey replaces the key the agent holds for the vault with the one the vault was
+// re-encrypted with, so the vault stays unlocked. Nothing happens if the vault is not
+// unlocked in the agent.
+func refreshAgentKey(vault *storage.VaultManager) {
+	client, err := agentClient()
+	if err != nil {
+		return
+	}
+	if _, err := client.Key(vault.Path()); err != nil {
+		return
+	}
+	if err := client.AddKey(vault.Path(), vault.Key()); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to update the agent, run mpass unlock again: %v\n", err)
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/ui"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [generation]",
+	Short: "List vault backups or roll back to one",
+	Long: `Without arguments, list the previous generations of the vault kept as backups.
+With a generation number, replace the vault with that backup. The current vault is
+kept as the newest backup, so a restore can be undone; for that reason restoring is
+refused when backups are disabled in the config file.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRestore,
+}
+
+// runRestore executes the "restore" command, listing the available backups or restoring
+// the selected generation after confirmation.
+func runRestore(_ *cobra.Command, args []string) error {
+	vault, err := newVaultManager()
+	if err != nil {
+		return err
+	}
+
+	backups, err := vault.Backups()
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	if len(args) == 0 {
+		if len(backups) == 0 {
+			fmt.Println("📭 No vault backups found")
+			return nil
+		}
+
+		fmt.Printf("🗄️  Found %d vault backups:\n\n", len(backups))
+		for _, backup := range backups {
+			fmt.Printf("%d. %s  %s  (%d bytes)\n", backup.Generation,
+				backup.ModTime.Format("2006-01-02 15:04:05"), backup.Path, backup.Size)
+		}
+		fmt.Println("\nRun 'mpass restore <generation>' to roll back to one of them.")
+		return nil
+	}
+
+	generation, err := strconv.Atoi(args[0])
+	if err != nil || generation <= 0 {
+		return fmt.Errorf("invalid backup generation: %s", args[0])
+	}
+
+	confirmed, err := ui.PromptConfirm(fmt.Sprintf("Replace the current vault with backup %d?", generation))
+	if err != nil {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if !confirmed {
+		fmt.Println("Restore cancelled.")
+		return nil
+	}
+
+	if err := vault.Restore(generation); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	fmt.Printf("✅ Vault restored from backup %d\n", generation)
+	return nil
+}
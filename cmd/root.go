@@ -29,10 +29,24 @@ func Execute() {
 // init initializes the root command by adding subcommands to it.
 // This function is automatically called when the package is initialized.
 func init() {
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(getCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(otpCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(mvCmd)
+	rootCmd.AddCommand(passwdCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(vaultsCmd)
+	rootCmd.AddCommand(unlockCmd)
+	rootCmd.AddCommand(lockCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(clipboardClearCmd)
+	rootCmd.AddCommand(clipboardServeCmd)
 }
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/agent"
+	"mpass/internal/storage"
+	"mpass/internal/ui"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var unlockIdleTimeout time.Duration
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Unlock the vault in the agent",
+	Long: `Ask for the master password once and hand the vault key to the unlock agent, starting
+the agent in the background if needed. Until the agent is locked or times out, other
+commands use the key instead of asking for the master password.`,
+	Args: cobra.NoArgs,
+	RunE: runUnlock,
+}
+
+// init registers the flags of the unlock command.
+func init() {
+	unlockCmd.Flags().DurationVar(&unlockIdleTimeout, "idle-timeout", agent.DefaultIdleTimeout, "Idle timeout of the agent if it has to be started")
+}
+
+// runUnlock executes the "unlock" command. The master password is verified before the key
+// is handed to the agent.
+func runUnlock(_ *cobra.Command, _ []string) error {
+	vault, err := newVaultManager()
+	if err != nil {
+		return err
+	}
+	if !vault.Exists() {
+		return storage.ErrVaultNotFound
+	}
+
+	masterPassword, err := ui.ReadMasterPassword("Enter master password:", passwordSource)
+	if err != nil {
+		return fmt.Errorf("failed to get master password: %w", err)
+	}
+	if _, err := vault.Unlock(masterPassword); err != nil {
+		return fmt.Errorf("failed to unlock vault: %w", err)
+	}
+	if err := upgradeKDF(vault, masterPassword); err != nil {
+		return err
+	}
+
+	client, status, err := ensureAgent(unlockIdleTimeout)
+	if err != nil {
+		return err
+	}
+	// The upgrade may have re-encrypted the vault, so take the key from the manager
+	if err := client.AddKey(vault.Path(), vault.Key()); err != nil {
+		return fmt.Errorf("failed to hand key to agent: %w", err)
+	}
+
+	fmt.Printf("🔓 Vault unlocked: %s\n", vault.Path())
+	if status.IdleTimeout > 0 {
+		fmt.Printf("The agent locks it after %s without use, or run 'mpass lock'.\n", status.IdleTimeout)
+	}
+	return nil
+}
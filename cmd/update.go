@@ -3,71 +3,108 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"mpass/internal/models"
 	"mpass/internal/storage"
 	"mpass/internal/ui"
-	"time"
 )
 
-var updateCmd = &cobra.Command{
-	Use:   "update",
-	Short: "Update a password entry",
-	Long:  "Update a password entry with new details such as username, URL, or password",
-	RunE:  runUpdate,
+var (
+	updateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "Update a password entry",
+		Long:  "Update a password entry with new details such as username, URL, or password",
+		RunE:  runUpdate,
+	}
+	updateID   string
+	regenerate bool
+)
+
+func init() {
+	updateCmd.Flags().StringVar(&updateID, "id", "", "Update the entry with this ID (or unique ID prefix)")
+	updateCmd.Flags().BoolVar(&regenerate, "regenerate", false, "Replace the password with a generated one without prompting")
+	addShowFlag(updateCmd)
+	addPolicyFlags(updateCmd)
+	addFieldFlags(updateCmd, true)
 }
 
-func runUpdate(_ *cobra.Command, _ []string) error {
-	masterPassword, err := ui.PromptPassword("Enter master password:")
-	if err != nil {
-		return fmt.Errorf("failed to get master password: %w", err)
+func runUpdate(cmd *cobra.Command, _ []string) error {
+	if err := checkGenerateFlags(cmd, regenerate, "regenerate"); err != nil {
+		return err
 	}
 
-	vaultManager := storage.NewVault()
-
-	entries, err := vaultManager.GetAllEntries(masterPassword)
+	vaultManager, masterPassword, err := openVault()
 	if err != nil {
-		return fmt.Errorf("failed to load entries: %w", err)
-	}
-	if len(entries) == 0 {
-		return fmt.Errorf("No entries found in vault")
+		return err
 	}
 
-	selectedEntry, err := ui.SelectEntry(entries)
+	selectedEntry, err := selectEntry(vaultManager, updateID, masterPassword)
 	if err != nil {
-		return fmt.Errorf("failed to select entry: %w", err)
+		return err
+	}
+
+	schema := selectedEntry.Kind().Schema()
+	if regenerate && schema.Password == "" {
+		return fmt.Errorf("a %s has no password to regenerate", schema.Label)
+	}
+	if fieldFlagsChanged(cmd) || regenerate {
+		entry := *selectedEntry
+		if err := applyFieldFlags(cmd, &entry); err != nil {
+			return err
+		}
+		if regenerate {
+			return regeneratePassword(cmd, vaultManager, entry, masterPassword)
+		}
+		if err := vaultManager.UpdateEntry(entry, masterPassword); err != nil {
+			return fmt.Errorf("failed to save updated entry: %w", err)
+		}
+		fmt.Printf("✅ Entry %s updated\n", entry.Title())
+		return nil
 	}
 
 	fmt.Println("Leave any field blank to keep it unchanged.")
-	newUsername, _ := ui.PromptInput("New Username (current: " + selectedEntry.Username + "):")
-	newURL, _ := ui.PromptInput("New URL (current: " + selectedEntry.URL + "):")
-	newPassword, _ := ui.PromptPassword("New Password (leave blank so as not to change it):")
+	var newName, newUsername, newURL, newPassword string
+	if selectedEntry.Kind() != models.TypeLogin {
+		newName, _ = ui.PromptInput("New Name (current: " + selectedEntry.Name + "):")
+	}
+	if schema.Username != "" {
+		newUsername, _ = ui.PromptInput("New " + schema.Username + " (current: " + selectedEntry.Username + "):")
+	}
+	if schema.URL != "" {
+		newURL, _ = ui.PromptInput("New " + schema.URL + " (current: " + selectedEntry.URL + "):")
+	}
+	if schema.Password != "" {
+		newPassword, _ = ui.PromptPassword("New " + schema.Password + " (leave blank so as not to change it):")
+	}
 
 	updated := false
-	for i := range entries {
-		if entries[i].Username == selectedEntry.Username && entries[i].URL == selectedEntry.URL && entries[i].Password == selectedEntry.Password {
-			if newUsername != "" {
-				entries[i].Username = newUsername
-				updated = true
-			}
-			if newURL != "" {
-				entries[i].URL = newURL
-				updated = true
-			}
-			if newPassword != "" {
-				entries[i].Password = newPassword
-				updated = true
-			}
-			if updated {
-				entries[i].UpdatedAt = time.Now()
+	entry := *selectedEntry
+	if newName != "" {
+		entry.Name = newName
+		updated = true
+	}
+	if newUsername != "" {
+		entry.Username = newUsername
+		updated = true
+	}
+	if newURL != "" {
+		entry.URL = newURL
+		updated = true
+	}
+	if newPassword != "" {
+		if entry.Kind() == models.TypeLogin {
+			if err := checkStrength(newPassword); err != nil {
+				return err
 			}
-			break
 		}
+		entry.Password = newPassword
+		updated = true
 	}
 	if !updated {
 		fmt.Println("No changes were made.")
 		return nil
 	}
 
-	err = vaultManager.UpdateEntries(entries, masterPassword)
+	err = vaultManager.UpdateEntry(entry, masterPassword)
 	if err != nil {
 		return fmt.Errorf("failed to save updated entry: %w", err)
 	}
@@ -75,3 +112,24 @@ func runUpdate(_ *cobra.Command, _ []string) error {
 		selectedEntry.URL)
 	return nil
 }
+
+// regeneratePassword replaces the password of entry with a generated one and saves it.
+func regeneratePassword(cmd *cobra.Command, vaultManager *storage.VaultManager, entry models.PasswordEntry, masterPassword string) error {
+	password, err := generatePassword(cmd)
+	if err != nil {
+		return err
+	}
+	if entry.Kind() == models.TypeLogin {
+		if err := checkStrength(password); err != nil {
+			return err
+		}
+	}
+	entry.Password = password
+	if err := vaultManager.UpdateEntry(entry, masterPassword); err != nil {
+		return fmt.Errorf("failed to save updated entry: %w", err)
+	}
+
+	fmt.Printf("✅ New password generated and saved for %s\n", entry.Title())
+	printGenerated(password)
+	return nil
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"mpass/internal/config"
+	"mpass/internal/crypto"
+	"mpass/internal/models"
+	"mpass/internal/storage"
+	"mpass/internal/ui"
+	"os"
+	"time"
+)
+
+var (
+	vaultSelection config.Selection
+	kdfDefaults    = crypto.DefaultKDFParams()
+	kdfMemory      uint32
+	kdfTime        uint32
+	kdfParallelism uint8
+	lockTimeout    time.Duration
+	passwordSource = ui.PasswordSource{FD: -1}
+)
+
+// init registers the global flags that control how the vault is opened: which vault to use,
+// where the master password comes from, the key derivation parameters used when a vault is created
+// or re-encrypted, and the lock wait time.
+func init() {
+	rootCmd.PersistentFlags().StringVar(&vaultSelection.Vault, "vault", "", "Path of the vault file to use (overrides $MPASS_VAULT)")
+	rootCmd.PersistentFlags().StringVar(&vaultSelection.Profile, "profile", "", "Named vault from the config file to use")
+	rootCmd.PersistentFlags().Uint32Var(&kdfMemory, "kdf-memory", kdfDefaults.Memory/1024, "Argon2id memory in MiB for new or re-encrypted vaults")
+	rootCmd.PersistentFlags().Uint32Var(&kdfTime, "kdf-time", kdfDefaults.Time, "Argon2id number of passes for new or re-encrypted vaults")
+	rootCmd.PersistentFlags().Uint8Var(&kdfParallelism, "kdf-parallelism", kdfDefaults.Parallelism, "Argon2id parallelism for new or re-encrypted vaults")
+	rootCmd.PersistentFlags().StringVar(&passwordSource.File, "password-file", "", "Read the master password from the first line of this file")
+	rootCmd.PersistentFlags().IntVar(&passwordSource.FD, "password-fd", -1, "Read the master password from this file descriptor")
+	rootCmd.PersistentFlags().StringVar(&passwordSource.Env, "password-env", "", "Read the master password from this environment variable")
+	rootCmd.PersistentFlags().StringVar(&passwordSource.Command, "password-command", "", "Read the master password from the output of this shell command")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 10*time.Second, "How long to wait for another mpass process to release the vault")
+}
+
+// kdfParams returns the Argon2id parameters selected with the global flags.
+func kdfParams() crypto.KDFParams {
+	return crypto.KDFParams{
+		Algorithm:   crypto.KDFArgon2id,
+		Time:        kdfTime,
+		Memory:      kdfMemory * 1024,
+		Parallelism: kdfParallelism,
+	}
+}
+
+// loadedConfig caches the configuration file once it has been read.
+var loadedConfig *config.Config
+
+// loadConfig reads the user configuration file, or returns the cached copy.
+func loadConfig() (*config.Config, error) {
+	if loadedConfig == nil {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		loadedConfig = cfg
+	}
+	return loadedConfig, nil
+}
+
+// newVaultManager returns a VaultManager for the vault selected with --vault, --profile,
+// $MPASS_VAULT or the config file, configured with the key derivation flags and the number
+// of backups from the config file.
+func newVaultManager() (*storage.VaultManager, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	vaultPath, err := cfg.ResolveVault(vaultSelection)
+	if err != nil {
+		return nil, err
+	}
+
+	vault := storage.NewVault(vaultPath)
+	if err := vault.SetKDFParams(kdfParams()); err != nil {
+		return nil, fmt.Errorf("invalid key derivation settings: %w", err)
+	}
+	if err := vault.SetLockTimeout(lockTimeout); err != nil {
+		return nil, err
+	}
+	if err := vault.SetBackups(cfg.VaultBackups()); err != nil {
+		return nil, err
+	}
+	return vault, nil
+}
+
+// openVault returns an unlocked VaultManager for the selected vault together with the master
+// password, read from the configured password source or typed by the user. It fails before
+// prompting if the vault has not been created yet. When the vault is unlocked in the agent its
+// key is used instead and the returned master password is empty.
+// If the vault is still protected by PBKDF2, the user is offered to re-encrypt it with Argon2id
+// once the master password has been verified.
+func openVault() (*storage.VaultManager, string, error) {
+	vault, err := newVaultManager()
+	if err != nil {
+		return nil, "", err
+	}
+	if !vault.Exists() {
+		return nil, "", storage.ErrVaultNotFound
+	}
+	if unlockWithAgent(vault) {
+		return vault, "", nil
+	}
+
+	masterPassword, err := ui.ReadMasterPassword("Enter master password:", passwordSource)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get master password: %w", err)
+	}
+
+	// Unlocking first verifies the password and keeps the key, so that neither the upgrade
+	// nor later operations have to derive it again.
+	if _, err := vault.Unlock(masterPassword); err != nil {
+		return nil, "", fmt.Errorf("failed to unlock vault: %w", err)
+	}
+	if err := upgradeKDF(vault, masterPassword); err != nil {
+		return nil, "", err
+	}
+	return vault, masterPassword, nil
+}
+
+// upgradeKDF offers to re-encrypt a vault that is still protected by PBKDF2 with Argon2id.
+// vault must have been unlocked. Without a terminal to confirm on, the vault is left as it is.
+func upgradeKDF(vault *storage.VaultManager, masterPassword string) error {
+	needsUpgrade, err := vault.NeedsKDFUpgrade()
+	if err != nil || !needsUpgrade {
+		return err
+	}
+	if !ui.IsInteractive() {
+		fmt.Fprintln(os.Stderr, "⚠️  This vault uses PBKDF2; run mpass interactively to re-encrypt it with Argon2id.")
+		return nil
+	}
+
+	upgraded, err := vault.UpgradeKDF(masterPassword, func() (bool, error) {
+		fmt.Println("⚠️  This vault uses PBKDF2, which is weak against GPU attacks.")
+		return ui.PromptConfirm("Re-encrypt it with Argon2id now?")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upgrade vault encryption: %w", err)
+	}
+	if upgraded {
+		fmt.Println("✅ Vault re-encrypted with Argon2id")
+	}
+	return nil
+}
+
+// selectEntry returns the entry with the given ID (or unique ID prefix), or lets the user
+// pick one from all entries in the vault when no ID is given.
+func selectEntry(vault *storage.VaultManager, id, masterPassword string) (*models.PasswordEntry, error) {
+	if id != "" {
+		entry, err := vault.GetEntry(id, masterPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entry: %w", err)
+		}
+		return entry, nil
+	}
+
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries found in vault")
+	}
+
+	selectedEntry, err := ui.SelectEntry(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select entry: %w", err)
+	}
+	return selectedEntry, nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var vaultsCmd = &cobra.Command{
+	Use:   "vaults",
+	Short: "List the known vaults",
+	Long: `List the default vault, the vault from $MPASS_VAULT and the named profiles
+from the config file. The vault selected by the current flags and environment is
+marked with an asterisk.`,
+	Args: cobra.NoArgs,
+	RunE: runVaults,
+}
+
+// runVaults executes the "vaults" command, printing every known vault with its path
+// and whether the vault file exists yet.
+func runVaults(_ *cobra.Command, _ []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	vaults, err := cfg.Vaults(vaultSelection)
+	if err != nil {
+		return fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	fmt.Printf("🗃️  Known vaults:\n\n")
+	for _, vault := range vaults {
+		marker := " "
+		if vault.Active {
+			marker = "*"
+		}
+		status := ""
+		if _, err := os.Stat(vault.Path); os.IsNotExist(err) {
+			status = "  (not created yet)"
+		}
+		fmt.Printf("%s %-12s %s%s\n", marker, vault.Name, vault.Path, status)
+	}
+
+	return nil
+}
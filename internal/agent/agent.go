@@ -0,0 +1,51 @@
+// Package agent implements the mpass unlock agent: a background process that keeps derived
+// vault keys in memory and hands them to other mpass commands over a per-user Unix socket,
+// so the master password only has to be typed once per session.
+package agent
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultIdleTimeout is how long the agent keeps keys without being used before it exits.
+const DefaultIdleTimeout = 15 * time.Minute
+
+var (
+	// ErrNotRunning is returned by the client when no agent is listening on the socket.
+	ErrNotRunning = errors.New("mpass agent is not running")
+	// ErrNotUnlocked is returned by the client when the agent holds no key for a vault.
+	ErrNotUnlocked = errors.New("vault is not unlocked in the agent")
+)
+
+// Requests understood by the agent.
+const (
+	opPing   = "ping"
+	opGetKey = "get"
+	opAddKey = "add"
+	opLock   = "lock"
+)
+
+// request is a single JSON message sent to the agent. Each connection carries one request
+// followed by one response.
+type request struct {
+	Op    string `json:"op"`
+	Vault string `json:"vault,omitempty"`
+	Key   []byte `json:"key,omitempty"`
+}
+
+// response is the agent's answer to a request.
+type response struct {
+	Error string `json:"error,omitempty"`
+	Key   []byte `json:"key,omitempty"`
+	// Vaults is the number of unlocked vaults, reported by ping.
+	Vaults int `json:"vaults,omitempty"`
+	// IdleTimeout is the agent's idle timeout, reported by ping.
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty"`
+}
+
+// Status describes a running agent.
+type Status struct {
+	Vaults      int           // number of vaults currently unlocked
+	IdleTimeout time.Duration // how long the agent stays up without being used
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startAgent starts an agent on a socket in a temporary directory and returns it together
+// with a channel that receives the result of Serve.
+func startAgent(t *testing.T, idleTimeout time.Duration) (*Server, <-chan error) {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir can exceed.
+	dir, err := os.MkdirTemp("", "mpass-agent")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	server, err := Listen(filepath.Join(dir, "run", "agent.sock"), idleTimeout)
+	if err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	done := make(chan error, 1)
+	go func() { done <- server.Serve() }()
+	return server, done
+}
+
+func waitStopped(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected agent to stop cleanly, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected agent to stop")
+	}
+}
+
+func TestAgentKeys(t *testing.T) {
+	server, _ := startAgent(t, time.Minute)
+	client := NewClient(server.Path())
+
+	if _, err := client.Key("/vaults/personal.enc"); !errors.Is(err, ErrNotUnlocked) {
+		t.Fatalf("Expected ErrNotUnlocked, got %v", err)
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	if err := client.AddKey("/vaults/personal.enc", key); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	got, err := client.Key("/vaults/./personal.enc")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(got) != string(key) {
+		t.Fatalf("Expected %q, got %q", key, got)
+	}
+	if _, err := client.Key("/vaults/work.enc"); !errors.Is(err, ErrNotUnlocked) {
+		t.Fatalf("Expected ErrNotUnlocked for another vault, got %v", err)
+	}
+
+	status, err := client.Ping()
+	if err != nil {
+		t.Fatalf("Failed to ping agent: %v", err)
+	}
+	if status.Vaults != 1 || status.IdleTimeout != time.Minute {
+		t.Fatalf("Expected 1 vault and a 1m timeout, got %+v", status)
+	}
+}
+
+func TestAgentSocketPermissions(t *testing.T) {
+	server, _ := startAgent(t, time.Minute)
+
+	info, err := os.Stat(server.Path())
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Expected socket permissions 0600, got %o", perm)
+	}
+	dirInfo, err := os.Stat(filepath.Dir(server.Path()))
+	if err != nil {
+		t.Fatalf("Failed to stat socket directory: %v", err)
+	}
+	if perm := dirInfo.Mode().Perm(); perm != 0700 {
+		t.Fatalf("Expected socket directory permissions 0700, got %o", perm)
+	}
+}
+
+func TestListenRequiresPrivateDir(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mpass-agent")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// An existing directory other users can enter is refused rather than trusted
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	if _, err := Listen(filepath.Join(dir, "agent.sock"), time.Minute); err == nil {
+		t.Fatal("Expected a directory accessible to others to be rejected")
+	}
+
+	link := dir + "-link"
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(link) })
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	if _, err := Listen(filepath.Join(link, "agent.sock"), time.Minute); err == nil {
+		t.Fatal("Expected a symlinked directory to be rejected")
+	}
+
+	server, err := Listen(filepath.Join(dir, "agent.sock"), time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	server.Close()
+}
+
+func TestCheckPeer(t *testing.T) {
+	server, _ := startAgent(t, time.Minute)
+
+	conn, err := net.Dial("unix", server.Path())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if err := checkPeer(conn); err != nil {
+		t.Fatalf("Expected a client of the same user to be accepted, got %v", err)
+	}
+
+	client, other := net.Pipe()
+	defer client.Close()
+	defer other.Close()
+	if err := checkPeer(other); err == nil {
+		t.Fatal("Expected a connection that is not a Unix socket to be rejected")
+	}
+}
+
+func TestAgentLock(t *testing.T) {
+	server, done := startAgent(t, time.Minute)
+	client := NewClient(server.Path())
+
+	if err := client.AddKey("/vaults/personal.enc", []byte("key")); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+	if err := client.Lock(); err != nil {
+		t.Fatalf("Failed to lock agent: %v", err)
+	}
+	waitStopped(t, done)
+
+	if _, err := client.Ping(); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Expected ErrNotRunning after lock, got %v", err)
+	}
+	if _, err := os.Stat(server.Path()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the socket to be removed, got %v", err)
+	}
+}
+
+func TestAgentIdleTimeout(t *testing.T) {
+	_, done := startAgent(t, 100*time.Millisecond)
+	waitStopped(t, done)
+}
+
+func TestListenWhileRunning(t *testing.T) {
+	server, _ := startAgent(t, time.Minute)
+
+	if _, err := Listen(server.Path(), time.Minute); err == nil {
+		t.Fatal("Expected a second agent on the same socket to be rejected")
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	server, done := startAgent(t, time.Minute)
+	path := server.Path()
+	if err := NewClient(path).Lock(); err != nil {
+		t.Fatalf("Failed to lock agent: %v", err)
+	}
+	waitStopped(t, done)
+
+	// Leave something behind at the socket path, as a crashed agent would
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+
+	restarted, err := Listen(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got %v", err)
+	}
+	restarted.Close()
+}
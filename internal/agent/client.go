@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// dialTimeout bounds how long the client waits for the agent to accept a connection.
+const dialTimeout = time.Second
+
+// Client talks to an agent listening on a Unix socket.
+type Client struct {
+	path string
+}
+
+// NewClient returns a client for the agent socket at path.
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// Ping checks that the agent is running and returns its status.
+// Returns ErrNotRunning if nothing is listening on the socket.
+func (c *Client) Ping() (Status, error) {
+	resp, err := c.call(request{Op: opPing})
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Vaults: resp.Vaults, IdleTimeout: resp.IdleTimeout}, nil
+}
+
+// Key returns the key the agent holds for the vault at vaultPath.
+// Returns ErrNotUnlocked if the vault has not been unlocked in the agent.
+func (c *Client) Key(vaultPath string) ([]byte, error) {
+	resp, err := c.call(request{Op: opGetKey, Vault: filepath.Clean(vaultPath)})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Key, nil
+}
+
+// AddKey hands the key of the vault at vaultPath to the agent.
+func (c *Client) AddKey(vaultPath string, key []byte) error {
+	_, err := c.call(request{Op: opAddKey, Vault: filepath.Clean(vaultPath), Key: key})
+	return err
+}
+
+// Lock makes the agent forget all keys and exit.
+func (c *Client) Lock() error {
+	_, err := c.call(request{Op: opLock})
+	return err
+}
+
+// call sends a request to the agent and decodes its response.
+func (c *Client) call(req request) (*response, error) {
+	conn, err := net.DialTimeout("unix", c.path, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to agent: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+
+	if resp.Error == ErrNotUnlocked.Error() {
+		return nil, ErrNotUnlocked
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
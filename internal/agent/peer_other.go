@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package agent
+
+import "net"
+
+// peerUID is not implemented on this platform; access to the agent is only restricted by the
+// permissions of the socket directory.
+func peerUID(conn *net.UnixConn) (int, error) {
+	return -1, errPeerUnknown
+}
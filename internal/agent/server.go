@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ioTimeout bounds how long a single client may take to send its request and read the answer.
+const ioTimeout = 5 * time.Second
+
+// Server holds unlocked vault keys and answers client requests on a Unix socket.
+type Server struct {
+	path        string
+	listener    net.Listener
+	idleTimeout time.Duration
+
+	mu     sync.Mutex
+	keys   map[string][]byte // vault path -> derived key
+	timer  *time.Timer
+	closed bool
+}
+
+// Listen creates the agent socket at path and returns a Server ready to Serve on it.
+// The socket is only accessible to the current user: its directory is created with 0700
+// if needed and must be owned by the user and closed to everyone else, and the socket is
+// created with 0600 permissions. A stale socket left behind by an agent that died is
+// replaced; if another agent is still answering on it, Listen fails.
+// An idleTimeout of zero keeps the agent running until it is locked.
+func Listen(path string, idleTimeout time.Duration) (*Server, error) {
+	if idleTimeout < 0 {
+		return nil, fmt.Errorf("idle timeout cannot be negative")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create agent directory: %w", err)
+	}
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("unsafe agent directory: %w", err)
+	}
+
+	if _, err := os.Lstat(path); err == nil {
+		if _, err := NewClient(path).Ping(); err == nil {
+			return nil, fmt.Errorf("an agent is already running on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale agent socket: %w", err)
+		}
+	}
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	return &Server{
+		path:        path,
+		listener:    listener,
+		idleTimeout: idleTimeout,
+		keys:        make(map[string][]byte),
+	}, nil
+}
+
+// Path returns the location of the agent socket.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve answers requests until the agent is locked, stays idle for longer than its idle
+// timeout, or Close is called. It returns nil in all of these cases.
+func (s *Server) Serve() error {
+	s.touch()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return fmt.Errorf("agent failed to accept connection: %w", err)
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close wipes all keys from memory, stops listening and removes the socket.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	for vault, key := range s.keys {
+		wipe(key)
+		delete(s.keys, vault)
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	err := s.listener.Close()
+	if rmErr := os.Remove(s.path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// touch restarts the idle timer; the agent exits once it fires.
+func (s *Server) touch() {
+	if s.idleTimeout == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.idleTimeout, func() { s.Close() })
+		return
+	}
+	s.timer.Reset(s.idleTimeout)
+}
+
+// handle reads one request from conn and writes the response.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	if err := checkPeer(conn); err != nil {
+		_ = json.NewEncoder(conn).Encode(response{Error: err.Error()})
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	s.touch()
+
+	resp, lock := s.dispatch(req)
+	_ = json.NewEncoder(conn).Encode(resp)
+
+	if lock {
+		s.Close()
+	}
+}
+
+// errPeerUnknown is returned by peerUID on platforms that cannot identify the client.
+var errPeerUnknown = errors.New("cannot identify the client on this platform")
+
+// checkPeer makes sure the client at the other end of conn runs as the same user as the
+// agent. Where the platform cannot tell, the permissions of the socket directory have to do.
+func checkPeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("agent only accepts Unix socket connections")
+	}
+	uid, err := peerUID(unixConn)
+	if errors.Is(err, errPeerUnknown) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to identify client: %w", err)
+	}
+	if uid != os.Getuid() {
+		return fmt.Errorf("permission denied: client runs as user %d", uid)
+	}
+	return nil
+}
+
+// dispatch executes a request and reports whether the agent should shut down afterwards.
+func (s *Server) dispatch(req request) (response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Op {
+	case opPing:
+		return response{Vaults: len(s.keys), IdleTimeout: s.idleTimeout}, false
+	case opGetKey:
+		key, ok := s.keys[filepath.Clean(req.Vault)]
+		if !ok {
+			return response{Error: ErrNotUnlocked.Error()}, false
+		}
+		return response{Key: append([]byte(nil), key...)}, false
+	case opAddKey:
+		if req.Vault == "" || len(req.Key) == 0 {
+			return response{Error: "vault and key are required"}, false
+		}
+		vault := filepath.Clean(req.Vault)
+		if old, ok := s.keys[vault]; ok {
+			wipe(old)
+		}
+		s.keys[vault] = req.Key
+		return response{}, false
+	case opLock:
+		return response{}, true
+	default:
+		return response{Error: fmt.Sprintf("unknown request %q", req.Op)}, false
+	}
+}
+
+// wipe overwrites a key before it is dropped.
+func wipe(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
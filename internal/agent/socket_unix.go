@@ -0,0 +1,38 @@
+//go:build !windows
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkSocketDir makes sure dir is a real directory owned by the current user that no one else
+// can enter, so that nobody else can connect to or replace the socket inside it.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", dir)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("%s is accessible to other users (mode %04o), run chmod 700 %s", dir, perm, dir)
+	}
+	return nil
+}
+
+// listenUnix creates the Unix socket at path with 0600 permissions from the start, instead of
+// restricting them once it already accepts connections. The umask is process-wide, which is
+// fine in the agent process that calls this before doing anything else.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package agent
+
+import "net"
+
+// checkSocketDir accepts any directory: on Windows access to the socket is governed by the
+// ACLs of the user profile it lives in.
+func checkSocketDir(dir string) error {
+	return nil
+}
+
+// listenUnix creates the Unix socket at path.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,130 @@
+// Package audit reviews the entries of a vault for passwords that should be changed.
+package audit
+
+import (
+	"fmt"
+	"mpass/internal/models"
+	"mpass/internal/strength"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is the type of problem a finding reports.
+type Kind string
+
+const (
+	Empty     Kind = "empty"     // the entry has no password
+	Reused    Kind = "reused"    // the password is shared with other entries
+	Weak      Kind = "weak"      // the password scores below the minimum strength
+	Stale     Kind = "stale"     // the password has not been changed for longer than the maximum age
+	Duplicate Kind = "duplicate" // another entry has the same username and URL
+)
+
+// kindOrder is the order in which findings are reported, most urgent first.
+var kindOrder = map[Kind]int{Empty: 0, Reused: 1, Weak: 2, Stale: 3, Duplicate: 4}
+
+// Finding is a problem found with one entry.
+type Finding struct {
+	Kind     Kind   `json:"kind"`
+	EntryID  string `json:"entry_id"`
+	Title    string `json:"title"`
+	Username string `json:"username"`
+	URL      string `json:"url"`
+	Detail   string `json:"detail"`
+}
+
+// Options sets the thresholds of an audit.
+type Options struct {
+	MinScore int           // passwords scoring below this are weak
+	MaxAge   time.Duration // passwords not updated for longer are stale; zero disables the check
+	Now      time.Time     // the time ages are measured from
+}
+
+// Run audits entries and returns the findings ordered by kind and then by entry position.
+// Passwords are only compared and estimated, never included in the findings. Only entries
+// whose type is used through its password, such as logins and API tokens, are audited.
+func Run(entries []models.PasswordEntry, opts Options) []Finding {
+	var findings []Finding
+	add := func(kind Kind, entry models.PasswordEntry, detail string) {
+		findings = append(findings, Finding{
+			Kind:     kind,
+			EntryID:  entry.ID,
+			Title:    entry.Title(),
+			Username: entry.Username,
+			URL:      entry.URL,
+			Detail:   detail,
+		})
+	}
+
+	byPassword := map[string][]int{}
+	byLogin := map[string][]int{}
+	for i, entry := range entries {
+		if !audited(entry) {
+			continue
+		}
+		if entry.Password != "" {
+			byPassword[entry.Password] = append(byPassword[entry.Password], i)
+		}
+		byLogin[loginKey(entry)] = append(byLogin[loginKey(entry)], i)
+	}
+
+	for i, entry := range entries {
+		if !audited(entry) {
+			continue
+		}
+		if entry.Password == "" {
+			add(Empty, entry, "no password set")
+		} else {
+			if others := otherIDs(entries, byPassword[entry.Password], i); len(others) > 0 {
+				add(Reused, entry, "same password as "+strings.Join(others, ", "))
+			}
+			if result := strength.Estimate(entry.Password); result.Score < opts.MinScore {
+				detail := result.String()
+				if result.Warning != "" {
+					detail += ": " + result.Warning
+				}
+				add(Weak, entry, detail)
+			}
+		}
+
+		// Only a new password makes an entry fresh, not edits such as moving it to a folder
+		if setAt := entry.PasswordSetAt(); opts.MaxAge > 0 {
+			if setAt.IsZero() {
+				add(Stale, entry, "password date unknown")
+			} else if age := opts.Now.Sub(setAt); age > opts.MaxAge {
+				add(Stale, entry, fmt.Sprintf("last changed %s (%d days ago)", setAt.Format("2006-01-02"), int(age.Hours()/24)))
+			}
+		}
+
+		if others := otherIDs(entries, byLogin[loginKey(entry)], i); len(others) > 0 {
+			add(Duplicate, entry, "same username and URL as "+strings.Join(others, ", "))
+		}
+	}
+
+	sort.SliceStable(findings, func(a, b int) bool {
+		return kindOrder[findings[a].Kind] < kindOrder[findings[b].Kind]
+	})
+	return findings
+}
+
+// audited reports whether entry is checked: its type is used through its password.
+func audited(entry models.PasswordEntry) bool {
+	return entry.Kind().Schema().Copy == models.CopyPassword
+}
+
+// loginKey identifies entries of the same type with the same username and URL, ignoring case.
+func loginKey(entry models.PasswordEntry) string {
+	return string(entry.Kind()) + "\x00" + strings.ToLower(entry.Username) + "\x00" + strings.ToLower(entry.URL)
+}
+
+// otherIDs returns the short IDs of the entries at positions, leaving out self.
+func otherIDs(entries []models.PasswordEntry, positions []int, self int) []string {
+	var ids []string
+	for _, i := range positions {
+		if i != self {
+			ids = append(ids, entries[i].ShortID())
+		}
+	}
+	return ids
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"mpass/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// kinds returns the kinds found for each entry ID.
+func kinds(findings []Finding) map[string][]Kind {
+	found := map[string][]Kind{}
+	for _, f := range findings {
+		found[f.EntryID] = append(found[f.EntryID], f.Kind)
+	}
+	return found
+}
+
+func TestRun(t *testing.T) {
+	entries := []models.PasswordEntry{
+		{ID: "aaaaaaaa-1", Username: "rob", URL: "github.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now},
+		{ID: "bbbbbbbb-2", Username: "rob", URL: "gitlab.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now},
+		{ID: "cccccccc-3", Username: "alice", URL: "example.com", Password: "password", CreatedAt: now},
+		{ID: "dddddddd-4", Username: "bob", URL: "example.org", Password: "", CreatedAt: now},
+		{ID: "eeeeeeee-5", Username: "carol", URL: "old.example.com", Password: "Qm8!vR2#nL5@wK", CreatedAt: now.AddDate(-2, 0, 0)},
+		{ID: "ffffffff-6", Username: "Rob", URL: "GitHub.com", Password: "Hj4$tY7!pW2&zX", CreatedAt: now},
+	}
+
+	findings := Run(entries, Options{MinScore: 3, MaxAge: 365 * 24 * time.Hour, Now: now})
+	found := kinds(findings)
+
+	expected := map[string][]Kind{
+		"aaaaaaaa-1": {Reused, Duplicate},
+		"bbbbbbbb-2": {Reused},
+		"cccccccc-3": {Weak},
+		"dddddddd-4": {Empty},
+		"eeeeeeee-5": {Stale},
+		"ffffffff-6": {Duplicate},
+	}
+	for id, want := range expected {
+		got := found[id]
+		if len(got) != len(want) {
+			t.Fatalf("Expected %v for %s, got %v", want, id, got)
+		}
+		for _, kind := range want {
+			if !containsKind(got, kind) {
+				t.Fatalf("Expected %v for %s, got %v", want, id, got)
+			}
+		}
+	}
+
+	for i := 1; i < len(findings); i++ {
+		if kindOrder[findings[i-1].Kind] > kindOrder[findings[i].Kind] {
+			t.Fatalf("Findings should be ordered by kind, got %s before %s", findings[i-1].Kind, findings[i].Kind)
+		}
+	}
+	for _, f := range findings {
+		if strings.Contains(f.Detail, "x7#Kp2") {
+			t.Fatalf("Findings must not contain passwords, got %q", f.Detail)
+		}
+	}
+}
+
+func TestRunReusedNamesOtherEntries(t *testing.T) {
+	entries := []models.PasswordEntry{
+		{ID: "aaaaaaaa-1", URL: "a.example.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now},
+		{ID: "bbbbbbbb-2", URL: "b.example.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now},
+		{ID: "cccccccc-3", URL: "c.example.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now},
+	}
+	findings := Run(entries, Options{Now: now})
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].Kind != Reused || findings[0].Detail != "same password as bbbbbbbb, cccccccc" {
+		t.Fatalf("Unexpected finding: %+v", findings[0])
+	}
+}
+
+func TestRunStale(t *testing.T) {
+	entries := []models.PasswordEntry{
+		{ID: "aaaaaaaa-1", URL: "a.example.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "bbbbbbbb-2", URL: "b.example.com", Password: "Qm8!vR2#nL5@wK"},
+		// Edited recently, but the password itself was last changed long ago
+		{ID: "cccccccc-3", URL: "c.example.com", Password: "Hj4$tY7!pW2&zX", CreatedAt: now.AddDate(-2, 0, 0), UpdatedAt: now,
+			History: []models.PreviousPassword{{Password: "old", ReplacedAt: now.Add(-72 * time.Hour)}}},
+		// Created long ago, but with a new password
+		{ID: "dddddddd-4", URL: "d.example.com", Password: "Pz3@mQ8!wR5#kL", CreatedAt: now.AddDate(-2, 0, 0),
+			History: []models.PreviousPassword{{Password: "old", ReplacedAt: now.Add(-time.Hour)}}},
+	}
+
+	if findings := Run(entries, Options{Now: now}); len(findings) != 0 {
+		t.Fatalf("A zero MaxAge should disable the stale check, got %+v", findings)
+	}
+
+	findings := Run(entries, Options{MaxAge: 24 * time.Hour, Now: now})
+	if len(findings) != 3 {
+		t.Fatalf("Expected all but the entry with a new password to be stale, got %+v", findings)
+	}
+	if findings[0].Detail != "last changed 2025-05-30 (2 days ago)" {
+		t.Fatalf("Unexpected detail: %q", findings[0].Detail)
+	}
+	if findings[1].Detail != "password date unknown" {
+		t.Fatalf("Unexpected detail: %q", findings[1].Detail)
+	}
+	if findings[2].EntryID != "cccccccc-3" || findings[2].Detail != "last changed 2025-05-29 (3 days ago)" {
+		t.Fatalf("Expected the password date rather than the last edit, got %+v", findings[2])
+	}
+}
+
+func TestRunSkipsTypesWithoutPassword(t *testing.T) {
+	entries := []models.PasswordEntry{
+		{ID: "aaaaaaaa-1", Type: models.TypeNote, Name: "Recovery codes", Notes: "1111 2222"},
+		{ID: "bbbbbbbb-2", Type: models.TypeCard, Name: "Visa"},
+		{ID: "cccccccc-3", Type: models.TypeCard, Name: "Visa"},
+		{ID: "dddddddd-4", Type: models.TypeAPIToken, Name: "CI token", URL: "api.example.com"},
+	}
+	findings := Run(entries, Options{MinScore: 3, Now: now})
+	if len(findings) != 1 || findings[0].EntryID != "dddddddd-4" || findings[0].Kind != Empty {
+		t.Fatalf("Expected only the empty API token to be reported, got %+v", findings)
+	}
+	if findings[0].Title != "CI token" {
+		t.Fatalf("Expected the finding to carry the entry title, got %q", findings[0].Title)
+	}
+}
+
+func TestRunClean(t *testing.T) {
+	entries := []models.PasswordEntry{
+		{ID: "aaaaaaaa-1", Username: "rob", URL: "github.com", Password: "x7#Kp2$vLq9@Zt", CreatedAt: now},
+		{ID: "bbbbbbbb-2", Username: "rob", URL: "gitlab.com", Password: "Qm8!vR2#nL5@wK", CreatedAt: now},
+	}
+	if findings := Run(entries, Options{MinScore: 4, MaxAge: time.Hour, Now: now}); len(findings) != 0 {
+		t.Fatalf("Expected no findings, got %+v", findings)
+	}
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mpass/internal/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	configDir  = ".mpass"
+	configFile = "config.json"
+	vaultFile  = "vault.enc"
+	agentSock  = "agent.sock"
+
+	// ConfigEnv overrides the location of the configuration file.
+	ConfigEnv = "MPASS_CONFIG"
+	// VaultEnv selects the vault file when neither --vault nor --profile is given.
+	VaultEnv = "MPASS_VAULT"
+	// AgentSocketEnv overrides the location of the unlock agent socket.
+	AgentSocketEnv = "MPASS_AGENT_SOCK"
+
+	// DefaultClipboardClearAfter is how long a copied secret stays on the clipboard
+	// when the config file does not say otherwise.
+	DefaultClipboardClearAfter = 45 * time.Second
+	// DefaultPasswordMaxAge is how long a password may go unchanged before audit reports it.
+	DefaultPasswordMaxAge = 365 * 24 * time.Hour
+	// DefaultAuditMinScore is the strength score below which audit reports a password as weak
+	// when passwords.audit_min_score is not set.
+	DefaultAuditMinScore = 3
+)
+
+// Config is the user configuration stored in ~/.mpass/config.json.
+type Config struct {
+	// DefaultProfile is used when no vault is selected explicitly.
+	DefaultProfile string `json:"default_profile,omitempty"`
+	// Profiles maps profile names to named vaults.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// Clipboard holds the clipboard settings.
+	Clipboard Clipboard `json:"clipboard"`
+	// Passwords holds the checks applied to entry passwords.
+	Passwords Passwords `json:"passwords"`
+	// Backups is how many previous generations of the vault are kept as vault.enc.1..N.
+	// Zero disables backups.
+	Backups *int `json:"backups,omitempty"`
+}
+
+// Clipboard configures how secrets copied to the clipboard are handled.
+type Clipboard struct {
+	// ClearAfter is how long a copied secret stays on the clipboard, such as "45s" or "2m".
+	// Zero keeps it until something else is copied.
+	ClearAfter *Duration `json:"clear_after,omitempty"`
+}
+
+// Passwords configures the checks applied to passwords saved with add and update, and the
+// ones audit applies to the passwords already in the vault.
+type Passwords struct {
+	// MinScore is the lowest strength score, from 0 to 4, add and update accept for a new
+	// password. Zero accepts any password.
+	MinScore int `json:"min_score,omitempty"`
+	// AuditMinScore is the strength score, from 0 to 4, below which audit reports a password
+	// as weak. Zero disables the check.
+	AuditMinScore *int `json:"audit_min_score,omitempty"`
+	// MaxAge is how long a password may go unchanged before audit reports it as stale,
+	// such as "2160h". Zero disables the check.
+	MaxAge *Duration `json:"max_age,omitempty"`
+}
+
+// MaxPasswordScore is the highest strength score a password can have.
+const MaxPasswordScore = 4
+
+// Duration is a time.Duration written in the config file as a string such as "45s".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string accepted by time.ParseDuration.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"45s\"")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration cannot be negative: %s", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration in the same string form UnmarshalJSON reads.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Profile is a named vault, such as "work" or "personal".
+type Profile struct {
+	Vault string `json:"vault"`
+}
+
+// VaultInfo describes a vault known to mpass.
+type VaultInfo struct {
+	Name   string // profile name, or "default" / "$MPASS_VAULT"
+	Path   string
+	Active bool // whether this vault would be used with the current flags and environment
+}
+
+// Selection holds the explicit vault choices made on the command line.
+type Selection struct {
+	Vault   string // --vault
+	Profile string // --profile
+}
+
+// Path returns the location of the configuration file: $MPASS_CONFIG if set,
+// otherwise ~/.mpass/config.json.
+func Path() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return ExpandPath(path)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, configDir, configFile), nil
+}
+
+// DefaultVaultPath returns ~/.mpass/vault.enc, the vault used when nothing else is configured.
+func DefaultVaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, configDir, vaultFile), nil
+}
+
+// AgentSocketPath returns the Unix socket the unlock agent listens on: $MPASS_AGENT_SOCK if set,
+// otherwise $XDG_RUNTIME_DIR/mpass/agent.sock, falling back to ~/.mpass/agent.sock.
+func AgentSocketPath() (string, error) {
+	if path := os.Getenv(AgentSocketEnv); path != "" {
+		return ExpandPath(path)
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "mpass", agentSock), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, configDir, agentSock), nil
+}
+
+// Load reads the configuration file from Path. A missing file yields an empty configuration.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads the configuration from the given file. A missing file yields an empty configuration.
+// Returns an error if the file cannot be read or is not valid JSON.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if cfg.Passwords.MinScore < 0 || cfg.Passwords.MinScore > MaxPasswordScore {
+		return nil, fmt.Errorf("invalid config file %s: passwords.min_score must be between 0 and %d", path, MaxPasswordScore)
+	}
+	if score := cfg.Passwords.AuditMinScore; score != nil && (*score < 0 || *score > MaxPasswordScore) {
+		return nil, fmt.Errorf("invalid config file %s: passwords.audit_min_score must be between 0 and %d", path, MaxPasswordScore)
+	}
+	if cfg.Backups != nil && *cfg.Backups < 0 {
+		return nil, fmt.Errorf("invalid config file %s: backups cannot be negative", path)
+	}
+	return &cfg, nil
+}
+
+// ExpandPath expands a leading "~" and environment variables in path and makes it absolute.
+func ExpandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+	return filepath.Abs(path)
+}
+
+// ClipboardClearAfter returns how long copied secrets stay on the clipboard: the configured
+// clipboard.clear_after, or DefaultClipboardClearAfter if it is not set.
+func (c *Config) ClipboardClearAfter() time.Duration {
+	if c.Clipboard.ClearAfter == nil {
+		return DefaultClipboardClearAfter
+	}
+	return time.Duration(*c.Clipboard.ClearAfter)
+}
+
+// VaultBackups returns how many previous generations of the vault are kept: the configured
+// backups, or storage.DefaultBackups if it is not set.
+func (c *Config) VaultBackups() int {
+	if c.Backups == nil {
+		return storage.DefaultBackups
+	}
+	return *c.Backups
+}
+
+// PasswordMaxAge returns how long a password may go unchanged before audit reports it: the
+// configured passwords.max_age, or DefaultPasswordMaxAge if it is not set.
+func (c *Config) PasswordMaxAge() time.Duration {
+	if c.Passwords.MaxAge == nil {
+		return DefaultPasswordMaxAge
+	}
+	return time.Duration(*c.Passwords.MaxAge)
+}
+
+// AuditMinScore returns the strength score below which audit reports a password as weak:
+// the configured passwords.audit_min_score, or DefaultAuditMinScore if it is not set.
+func (c *Config) AuditMinScore() int {
+	if c.Passwords.AuditMinScore == nil {
+		return DefaultAuditMinScore
+	}
+	return *c.Passwords.AuditMinScore
+}
+
+// profilePath returns the expanded vault path of the named profile.
+func (c *Config) profilePath(name string) (string, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return "", fmt.Errorf("unknown profile %q", name)
+	}
+	if profile.Vault == "" {
+		return "", fmt.Errorf("profile %q has no vault path", name)
+	}
+	return ExpandPath(profile.Vault)
+}
+
+// ResolveVault returns the vault path to use, in order of precedence: the --vault flag,
+// the --profile flag, the MPASS_VAULT environment variable, the default profile from the
+// configuration file and finally ~/.mpass/vault.enc.
+func (c *Config) ResolveVault(sel Selection) (string, error) {
+	if sel.Vault != "" {
+		return ExpandPath(sel.Vault)
+	}
+	if sel.Profile != "" {
+		return c.profilePath(sel.Profile)
+	}
+	if path := os.Getenv(VaultEnv); path != "" {
+		return ExpandPath(path)
+	}
+	if c.DefaultProfile != "" {
+		return c.profilePath(c.DefaultProfile)
+	}
+	return DefaultVaultPath()
+}
+
+// Vaults lists the default vault, the vault from MPASS_VAULT (if set) and every profile,
+// marking the one ResolveVault would pick for the given selection as active.
+func (c *Config) Vaults(sel Selection) ([]VaultInfo, error) {
+	active, err := c.ResolveVault(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultPath, err := DefaultVaultPath()
+	if err != nil {
+		return nil, err
+	}
+	vaults := []VaultInfo{{Name: "default", Path: defaultPath}}
+
+	if path := os.Getenv(VaultEnv); path != "" {
+		expanded, err := ExpandPath(path)
+		if err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, VaultInfo{Name: "$" + VaultEnv, Path: expanded})
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path, err := c.profilePath(name)
+		if err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, VaultInfo{Name: name, Path: path})
+	}
+
+	if sel.Vault != "" {
+		vaults = append(vaults, VaultInfo{Name: "--vault", Path: active})
+	}
+
+	// Prefer marking the entry the selection refers to, in case several share a path
+	activeName := ""
+	switch {
+	case sel.Vault != "":
+		activeName = "--vault"
+	case sel.Profile != "":
+		activeName = sel.Profile
+	case os.Getenv(VaultEnv) != "":
+		activeName = "$" + VaultEnv
+	case c.DefaultProfile != "":
+		activeName = c.DefaultProfile
+	default:
+		activeName = "default"
+	}
+	for i := range vaults {
+		vaults[i].Active = vaults[i].Name == activeName
+	}
+
+	return vaults, nil
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"mpass/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Missing config should not be an error: %v", err)
+	}
+	if cfg.DefaultProfile != "" || len(cfg.Profiles) != 0 {
+		t.Fatalf("Missing config should be empty, got %+v", cfg)
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	if _, err := LoadFile(writeConfig(t, "{not json")); err == nil {
+		t.Fatal("Invalid JSON should be rejected")
+	}
+}
+
+func TestClipboardClearAfter(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `{}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.ClipboardClearAfter(); got != DefaultClipboardClearAfter {
+		t.Fatalf("Expected default %s, got %s", DefaultClipboardClearAfter, got)
+	}
+
+	cfg, err = LoadFile(writeConfig(t, `{"clipboard": {"clear_after": "2m"}}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.ClipboardClearAfter(); got != 2*time.Minute {
+		t.Fatalf("Expected 2m0s, got %s", got)
+	}
+
+	cfg, err = LoadFile(writeConfig(t, `{"clipboard": {"clear_after": "0"}}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.ClipboardClearAfter(); got != 0 {
+		t.Fatalf("Expected clearing to be disabled, got %s", got)
+	}
+
+	for _, invalid := range []string{`"soon"`, `"-5s"`, `45`} {
+		if _, err := LoadFile(writeConfig(t, `{"clipboard": {"clear_after": `+invalid+`}}`)); err == nil {
+			t.Fatalf("Expected clear_after %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestPasswordsMinScore(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `{"passwords": {"min_score": 3}}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Passwords.MinScore != 3 {
+		t.Fatalf("Expected min_score 3, got %d", cfg.Passwords.MinScore)
+	}
+	if got := (&Config{}).AuditMinScore(); got != DefaultAuditMinScore {
+		t.Fatalf("Expected default audit score %d, got %d", DefaultAuditMinScore, got)
+	}
+
+	// The two scores are independent, and 0 means no check for both
+	cfg, err = LoadFile(writeConfig(t, `{"passwords": {"min_score": 2, "audit_min_score": 0}}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Passwords.MinScore != 2 || cfg.AuditMinScore() != 0 {
+		t.Fatalf("Expected min_score 2 and audit_min_score 0, got %d and %d", cfg.Passwords.MinScore, cfg.AuditMinScore())
+	}
+
+	for _, key := range []string{"min_score", "audit_min_score"} {
+		for _, invalid := range []string{`-1`, `5`, `"3"`} {
+			if _, err := LoadFile(writeConfig(t, `{"passwords": {"`+key+`": `+invalid+`}}`)); err == nil {
+				t.Fatalf("Expected %s %s to be rejected", key, invalid)
+			}
+		}
+	}
+}
+
+func TestPasswordMaxAge(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, `{}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.PasswordMaxAge(); got != DefaultPasswordMaxAge {
+		t.Fatalf("Expected default %s, got %s", DefaultPasswordMaxAge, got)
+	}
+
+	cfg, err = LoadFile(writeConfig(t, `{"passwords": {"max_age": "2160h"}}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.PasswordMaxAge(); got != 90*24*time.Hour {
+		t.Fatalf("Expected 2160h0m0s, got %s", got)
+	}
+}
+
+func TestVaultBackups(t *testing.T) {
+	if got := (&Config{}).VaultBackups(); got != storage.DefaultBackups {
+		t.Fatalf("Expected default %d backups, got %d", storage.DefaultBackups, got)
+	}
+
+	cfg, err := LoadFile(writeConfig(t, `{"backups": 0}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := cfg.VaultBackups(); got != 0 {
+		t.Fatalf("Expected backups to be disabled, got %d", got)
+	}
+
+	if _, err := LoadFile(writeConfig(t, `{"backups": -1}`)); err == nil {
+		t.Fatal("Expected negative backups to be rejected")
+	}
+}
+
+func TestPathFromEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	t.Setenv(ConfigEnv, path)
+
+	got, err := Path()
+	if err != nil {
+		t.Fatalf("Failed to get config path: %v", err)
+	}
+	if got != path {
+		t.Fatalf("Expected %s, got %s", path, got)
+	}
+}
+
+func TestAgentSocketPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(AgentSocketEnv, "")
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	got, err := AgentSocketPath()
+	if err != nil {
+		t.Fatalf("Failed to get agent socket path: %v", err)
+	}
+	if want := filepath.Join(home, ".mpass", "agent.sock"); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	if got, _ := AgentSocketPath(); got != filepath.Join(runtimeDir, "mpass", "agent.sock") {
+		t.Fatalf("Expected the socket in $XDG_RUNTIME_DIR, got %s", got)
+	}
+
+	custom := filepath.Join(t.TempDir(), "custom.sock")
+	t.Setenv(AgentSocketEnv, custom)
+	if got, _ := AgentSocketPath(); got != custom {
+		t.Fatalf("Expected %s, got %s", custom, got)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("VAULTS", "/srv/vaults")
+
+	got, err := ExpandPath("~/work.enc")
+	if err != nil {
+		t.Fatalf("Failed to expand path: %v", err)
+	}
+	if got != filepath.Join(homeDir, "work.enc") {
+		t.Fatalf("Expected ~ to expand to the home directory, got %s", got)
+	}
+
+	got, _ = ExpandPath("$VAULTS/shared.enc")
+	if got != "/srv/vaults/shared.enc" {
+		t.Fatalf("Expected environment variables to be expanded, got %s", got)
+	}
+}
+
+func TestResolveVaultPrecedence(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv(VaultEnv, "")
+
+	cfg, err := LoadFile(writeConfig(t, `{
+		"default_profile": "personal",
+		"profiles": {
+			"personal": {"vault": "/vaults/personal.enc"},
+			"work": {"vault": "/vaults/work.enc"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		sel  Selection
+		env  string
+		want string
+	}{
+		{"default profile", Selection{}, "", "/vaults/personal.enc"},
+		{"environment over default profile", Selection{}, "/env/vault.enc", "/env/vault.enc"},
+		{"profile over environment", Selection{Profile: "work"}, "/env/vault.enc", "/vaults/work.enc"},
+		{"vault flag over everything", Selection{Vault: "/flag/vault.enc", Profile: "work"}, "/env/vault.enc", "/flag/vault.enc"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv(VaultEnv, tt.env)
+		got, err := cfg.ResolveVault(tt.sel)
+		if err != nil {
+			t.Fatalf("%s: failed to resolve vault: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := cfg.ResolveVault(Selection{Profile: "missing"}); err == nil {
+		t.Fatal("Unknown profile should be rejected")
+	}
+}
+
+func TestResolveVaultDefault(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv(VaultEnv, "")
+
+	got, err := (&Config{}).ResolveVault(Selection{})
+	if err != nil {
+		t.Fatalf("Failed to resolve vault: %v", err)
+	}
+	if got != filepath.Join(homeDir, ".mpass", "vault.enc") {
+		t.Fatalf("Expected the default vault, got %s", got)
+	}
+}
+
+func TestVaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(VaultEnv, "")
+
+	cfg := &Config{Profiles: map[string]Profile{
+		"work":     {Vault: "/vaults/work.enc"},
+		"personal": {Vault: "/vaults/personal.enc"},
+	}}
+
+	vaults, err := cfg.Vaults(Selection{Profile: "work"})
+	if err != nil {
+		t.Fatalf("Failed to list vaults: %v", err)
+	}
+
+	names := []string{"default", "personal", "work"}
+	if len(vaults) != len(names) {
+		t.Fatalf("Expected %d vaults, got %d", len(names), len(vaults))
+	}
+	for i, name := range names {
+		if vaults[i].Name != name {
+			t.Fatalf("Expected vault %d to be %s, got %s", i, name, vaults[i].Name)
+		}
+		if vaults[i].Active != (name == "work") {
+			t.Fatalf("Only the work vault should be active, got %+v", vaults[i])
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KDF identifies the key derivation function used to turn a master password into a key.
+type KDF uint8
+
+const (
+	// KDFPBKDF2SHA256 is PBKDF2 with HMAC-SHA-256.
+	KDFPBKDF2SHA256 KDF = 1
+	// KDFArgon2id is the memory-hard Argon2id function from RFC 9106.
+	KDFArgon2id KDF = 2
+)
+
+const (
+	// Default Argon2id parameters: 3 passes over 64 MiB using 4 lanes.
+	argon2Time        = 3
+	argon2Memory      = 64 * 1024 // KiB
+	argon2Parallelism = 4
+)
+
+// Upper bounds on the parameters accepted from a vault header, so that a tampered or corrupt
+// header cannot make unlocking take hours or exhaust memory.
+const (
+	maxPBKDF2Iterations  = 10_000_000
+	maxArgon2Time        = 64
+	maxArgon2Memory      = 4 * 1024 * 1024 // KiB, 4 GiB
+	maxArgon2Parallelism = 64
+)
+
+// String returns a human readable name for the key derivation function.
+func (k KDF) String() string {
+	switch k {
+	case KDFPBKDF2SHA256:
+		return "pbkdf2-sha256"
+	case KDFArgon2id:
+		return "argon2id"
+	default:
+		return fmt.Sprintf("kdf(%d)", uint8(k))
+	}
+}
+
+// Cipher identifies the authenticated cipher used to encrypt vault data.
+type Cipher uint8
+
+const (
+	// CipherAES256GCM is AES-256 in Galois/Counter Mode, as implemented by Encrypt and Decrypt.
+	CipherAES256GCM Cipher = 1
+)
+
+// String returns a human readable name for the cipher.
+func (c Cipher) String() string {
+	switch c {
+	case CipherAES256GCM:
+		return "aes-256-gcm"
+	default:
+		return fmt.Sprintf("cipher(%d)", uint8(c))
+	}
+}
+
+// KDFParams describes a key derivation function together with its tuning parameters.
+type KDFParams struct {
+	Algorithm   KDF
+	Iterations  uint32 // PBKDF2 iteration count
+	Time        uint32 // Argon2id number of passes
+	Memory      uint32 // Argon2id memory in KiB
+	Parallelism uint8  // Argon2id number of lanes
+}
+
+// DefaultKDFParams returns the key derivation parameters used for new vaults.
+func DefaultKDFParams() KDFParams {
+	return KDFParams{
+		Algorithm:   KDFArgon2id,
+		Time:        argon2Time,
+		Memory:      argon2Memory,
+		Parallelism: argon2Parallelism,
+	}
+}
+
+// PBKDF2Params returns the PBKDF2-SHA256 parameters used by DeriveKey.
+func PBKDF2Params() KDFParams {
+	return KDFParams{Algorithm: KDFPBKDF2SHA256, Iterations: iterations}
+}
+
+// String returns a short description of the algorithm and its parameters.
+func (p KDFParams) String() string {
+	switch p.Algorithm {
+	case KDFPBKDF2SHA256:
+		return fmt.Sprintf("%s (%d iterations)", p.Algorithm, p.Iterations)
+	case KDFArgon2id:
+		return fmt.Sprintf("%s (t=%d, m=%d KiB, p=%d)", p.Algorithm, p.Time, p.Memory, p.Parallelism)
+	default:
+		return p.Algorithm.String()
+	}
+}
+
+// DeriveKeyWithParams derives a key of keyLength bytes from a password and salt using
+// the algorithm and parameters described by params.
+// Returns an error if the algorithm is unknown or the parameters are invalid.
+func DeriveKeyWithParams(password string, salt []byte, params KDFParams) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch params.Algorithm {
+	case KDFPBKDF2SHA256:
+		return pbkdf2.Key([]byte(password), salt, int(params.Iterations), keyLength, sha256.New), nil
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Parallelism, keyLength), nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function: %s", params.Algorithm)
+	}
+}
+
+// Validate checks that the parameters are usable for key derivation and within the bounds
+// accepted from a vault header.
+func (p KDFParams) Validate() error {
+	switch p.Algorithm {
+	case KDFPBKDF2SHA256:
+		if p.Iterations == 0 {
+			return fmt.Errorf("pbkdf2 iterations must be greater than zero")
+		}
+		if p.Iterations > maxPBKDF2Iterations {
+			return fmt.Errorf("pbkdf2 iterations cannot exceed %d", maxPBKDF2Iterations)
+		}
+		return nil
+	case KDFArgon2id:
+		if p.Time == 0 {
+			return fmt.Errorf("argon2id time must be greater than zero")
+		}
+		if p.Parallelism == 0 {
+			return fmt.Errorf("argon2id parallelism must be greater than zero")
+		}
+		if p.Memory < 8*uint32(p.Parallelism) {
+			return fmt.Errorf("argon2id memory must be at least %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+		}
+		if p.Time > maxArgon2Time {
+			return fmt.Errorf("argon2id time cannot exceed %d", maxArgon2Time)
+		}
+		if p.Memory > maxArgon2Memory {
+			return fmt.Errorf("argon2id memory cannot exceed %d KiB", maxArgon2Memory)
+		}
+		if p.Parallelism > maxArgon2Parallelism {
+			return fmt.Errorf("argon2id parallelism cannot exceed %d", maxArgon2Parallelism)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key derivation function: %s", p.Algorithm)
+	}
+}
+
+// MarshalBinary encodes the algorithm-specific parameters in big-endian order.
+// The algorithm identifier itself is not included.
+func (p KDFParams) MarshalBinary() ([]byte, error) {
+	switch p.Algorithm {
+	case KDFPBKDF2SHA256:
+		return binary.BigEndian.AppendUint32(nil, p.Iterations), nil
+	case KDFArgon2id:
+		data := binary.BigEndian.AppendUint32(nil, p.Time)
+		data = binary.BigEndian.AppendUint32(data, p.Memory)
+		return append(data, p.Parallelism), nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function: %s", p.Algorithm)
+	}
+}
+
+// UnmarshalKDFParams decodes parameters produced by KDFParams.MarshalBinary for the given algorithm.
+// Returns an error if the algorithm is unknown or the data is malformed.
+func UnmarshalKDFParams(algorithm KDF, data []byte) (KDFParams, error) {
+	params := KDFParams{Algorithm: algorithm}
+
+	switch algorithm {
+	case KDFPBKDF2SHA256:
+		if len(data) != 4 {
+			return params, fmt.Errorf("invalid pbkdf2 parameters length: %d", len(data))
+		}
+		params.Iterations = binary.BigEndian.Uint32(data)
+	case KDFArgon2id:
+		if len(data) != 9 {
+			return params, fmt.Errorf("invalid argon2id parameters length: %d", len(data))
+		}
+		params.Time = binary.BigEndian.Uint32(data[0:4])
+		params.Memory = binary.BigEndian.Uint32(data[4:8])
+		params.Parallelism = data[8]
+	default:
+		return params, fmt.Errorf("unsupported key derivation function: %s", algorithm)
+	}
+
+	return params, params.Validate()
+}
@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeriveKeyWithParamsMatchesDeriveKey(t *testing.T) {
+	password := "test-password"
+	salt := []byte("test-salt-32-bytes-long-exactly!!")
+
+	key, err := DeriveKeyWithParams(password, salt, PBKDF2Params())
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+
+	if !bytes.Equal(key, DeriveKey(password, salt)) {
+		t.Fatal("PBKDF2 parameters should produce the same key as DeriveKey")
+	}
+}
+
+func TestDeriveKeyWithInvalidParams(t *testing.T) {
+	salt := []byte("test-salt")
+
+	if _, err := DeriveKeyWithParams("password", salt, KDFParams{Algorithm: KDFPBKDF2SHA256}); err == nil {
+		t.Fatal("Zero iterations should be rejected")
+	}
+
+	if _, err := DeriveKeyWithParams("password", salt, KDFParams{Algorithm: KDF(99), Iterations: 1}); err == nil {
+		t.Fatal("Unknown algorithm should be rejected")
+	}
+}
+
+func TestKDFParamsRoundTrip(t *testing.T) {
+	for _, params := range []KDFParams{
+		{Algorithm: KDFPBKDF2SHA256, Iterations: 123456},
+		{Algorithm: KDFArgon2id, Time: 2, Memory: 19 * 1024, Parallelism: 1},
+	} {
+		data, err := params.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Failed to marshal %s params: %v", params.Algorithm, err)
+		}
+
+		decoded, err := UnmarshalKDFParams(params.Algorithm, data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s params: %v", params.Algorithm, err)
+		}
+
+		if decoded != params {
+			t.Fatalf("Expected %+v, got %+v", params, decoded)
+		}
+
+		if _, err := UnmarshalKDFParams(params.Algorithm, data[:2]); err == nil {
+			t.Fatalf("Truncated %s params should be rejected", params.Algorithm)
+		}
+	}
+}
+
+func TestDeriveKeyArgon2id(t *testing.T) {
+	password := "test-password"
+	salt := []byte("test-salt-32-bytes-long-exactly!!")
+	params := KDFParams{Algorithm: KDFArgon2id, Time: 1, Memory: 64, Parallelism: 1}
+
+	key1, err := DeriveKeyWithParams(password, salt, params)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	if len(key1) != keyLength {
+		t.Fatalf("Expected key length %d, got %d", keyLength, len(key1))
+	}
+
+	key2, _ := DeriveKeyWithParams(password, salt, params)
+	if !bytes.Equal(key1, key2) {
+		t.Fatal("Same password, salt and params should produce same key")
+	}
+
+	params.Time = 2
+	key3, _ := DeriveKeyWithParams(password, salt, params)
+	if bytes.Equal(key1, key3) {
+		t.Fatal("Different params should produce different keys")
+	}
+
+	if bytes.Equal(key1, DeriveKey(password, salt)) {
+		t.Fatal("Argon2id and PBKDF2 should produce different keys")
+	}
+}
+
+func TestArgon2idParamsValidation(t *testing.T) {
+	invalid := []KDFParams{
+		{Algorithm: KDFArgon2id, Time: 0, Memory: 64, Parallelism: 1},
+		{Algorithm: KDFArgon2id, Time: 1, Memory: 64, Parallelism: 0},
+		{Algorithm: KDFArgon2id, Time: 1, Memory: 8, Parallelism: 4},
+		{Algorithm: KDFArgon2id, Time: maxArgon2Time + 1, Memory: 64, Parallelism: 1},
+		{Algorithm: KDFArgon2id, Time: 1, Memory: maxArgon2Memory + 1, Parallelism: 1},
+		{Algorithm: KDFArgon2id, Time: 1, Memory: 1024 * 1024, Parallelism: maxArgon2Parallelism + 1},
+		{Algorithm: KDFPBKDF2SHA256, Iterations: maxPBKDF2Iterations + 1},
+	}
+	for _, params := range invalid {
+		if err := params.Validate(); err == nil {
+			t.Fatalf("Expected %+v to be rejected", params)
+		}
+	}
+
+	if err := DefaultKDFParams().Validate(); err != nil {
+		t.Fatalf("Default params should be valid: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package generator generates random passwords that satisfy a character class policy.
+package generator
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultCharset is the character set used when a policy does not name one.
+const DefaultCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()"
+
+// Presets are named character sets for common kinds of passwords.
+var Presets = map[string]string{
+	"alnum":     "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	"hex":       "0123456789abcdef",
+	"base64url": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_",
+	"pin":       "0123456789",
+	"symbols":   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// PresetNames returns the names of the presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Preset returns the character set of the named preset.
+func Preset(name string) (string, error) {
+	charset, ok := Presets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return charset, nil
+}
+
+// Ambiguous lists the characters that are easily confused with each other.
+const Ambiguous = "0O1lI"
+
+// Policy describes the passwords to generate.
+type Policy struct {
+	Length           int    // number of characters
+	Charset          string // characters to choose from; DefaultCharset if empty
+	MinUpper         int    // minimum number of upper case letters
+	MinLower         int    // minimum number of lower case letters
+	MinDigits        int    // minimum number of digits
+	MinSymbols       int    // minimum number of other characters, such as punctuation
+	ExcludeAmbiguous bool   // leave out the characters in Ambiguous
+	Exclude          string // characters to leave out
+}
+
+// class is a character class a policy can require a minimum of.
+type class int
+
+const (
+	upper class = iota
+	lower
+	digit
+	symbol
+	numClasses
+)
+
+// classNames describes each class in error messages.
+var classNames = [numClasses]string{"upper case letters", "lower case letters", "digits", "symbols"}
+
+// classOf returns the class of r.
+func classOf(r rune) class {
+	switch {
+	case unicode.IsUpper(r):
+		return upper
+	case unicode.IsLower(r):
+		return lower
+	case unicode.IsDigit(r):
+		return digit
+	default:
+		return symbol
+	}
+}
+
+// minimums returns the minimum of every class.
+func (p Policy) minimums() [numClasses]int {
+	return [numClasses]int{p.MinUpper, p.MinLower, p.MinDigits, p.MinSymbols}
+}
+
+// Alphabet returns the characters a password can be made of: the distinct characters of the
+// charset, in order, without the excluded characters. Listing a character twice in the
+// charset does not make it more likely.
+func (p Policy) Alphabet() []rune {
+	charset := p.Charset
+	if charset == "" {
+		charset = DefaultCharset
+	}
+	exclude := p.Exclude
+	if p.ExcludeAmbiguous {
+		exclude += Ambiguous
+	}
+
+	var alphabet []rune
+	seen := map[rune]bool{}
+	for _, r := range charset {
+		if !seen[r] && !strings.ContainsRune(exclude, r) {
+			alphabet = append(alphabet, r)
+		}
+		seen[r] = true
+	}
+	return alphabet
+}
+
+// Validate checks that passwords satisfying the policy exist.
+func (p Policy) Validate() error {
+	if p.Length <= 0 {
+		return fmt.Errorf("length must be greater than zero")
+	}
+	if err := checkCharacters("character set", p.Charset); err != nil {
+		return err
+	}
+	if err := checkCharacters("excluded characters", p.Exclude); err != nil {
+		return err
+	}
+	alphabet := p.Alphabet()
+	if len(alphabet) == 0 {
+		return fmt.Errorf("the character set is empty")
+	}
+
+	var available [numClasses]bool
+	for _, r := range alphabet {
+		available[classOf(r)] = true
+	}
+	total := 0
+	for c, min := range p.minimums() {
+		if min < 0 {
+			return fmt.Errorf("the minimum number of %s cannot be negative", classNames[c])
+		}
+		if min > 0 && !available[c] {
+			return fmt.Errorf("the policy requires %s but the character set has none", classNames[c])
+		}
+		total += min
+	}
+	if total > p.Length {
+		return fmt.Errorf("the minimums add up to %d characters, more than the length of %d", total, p.Length)
+	}
+	return nil
+}
+
+// checkCharacters rejects a list of characters that is not valid UTF-8 or contains
+// whitespace or control characters, which cannot be typed reliably into a password field.
+func checkCharacters(what, chars string) error {
+	if !utf8.ValidString(chars) {
+		return fmt.Errorf("the %s is not valid UTF-8", what)
+	}
+	for _, r := range chars {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return fmt.Errorf("the %s contains the unprintable or blank character %U", what, r)
+		}
+	}
+	return nil
+}
+
+// Generate returns a password satisfying p. The minimum of every class is drawn from the
+// characters of that class, the rest from the whole alphabet, and the characters are then
+// shuffled, all with crypto/rand. Any policy that passes Validate succeeds.
+func Generate(p Policy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	alphabet := p.Alphabet()
+	var classes [numClasses][]rune
+	for _, r := range alphabet {
+		classes[classOf(r)] = append(classes[classOf(r)], r)
+	}
+
+	password := make([]rune, 0, p.Length)
+	for c, min := range p.minimums() {
+		for i := 0; i < min; i++ {
+			r, err := pick(classes[c])
+			if err != nil {
+				return "", err
+			}
+			password = append(password, r)
+		}
+	}
+	for len(password) < p.Length {
+		r, err := pick(alphabet)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, r)
+	}
+
+	// Fisher-Yates shuffle, so the required characters can end up anywhere
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// pick returns a character of chars chosen uniformly at random.
+func pick(chars []rune) (rune, error) {
+	i, err := randomIndex(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// randomIndex returns a uniformly random number in [0, n) from crypto/rand.
+func randomIndex(n int) (int, error) {
+	idx, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate the password: %w", err)
+	}
+	return int(idx.Int64()), nil
+}
@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestGenerateDefault(t *testing.T) {
+	password, err := Generate(Policy{Length: 16})
+	if err != nil {
+		t.Fatalf("Failed to generate password: %v", err)
+	}
+	if len(password) != 16 {
+		t.Fatalf("Expected 16 characters, got %q", password)
+	}
+	for _, r := range password {
+		if !strings.ContainsRune(DefaultCharset, r) {
+			t.Fatalf("Unexpected character %q in %q", r, password)
+		}
+	}
+}
+
+func TestGenerateMinimums(t *testing.T) {
+	policy := Policy{Length: 8, MinUpper: 2, MinLower: 1, MinDigits: 3, MinSymbols: 2}
+	for i := 0; i < 50; i++ {
+		password, err := Generate(policy)
+		if err != nil {
+			t.Fatalf("Failed to generate password: %v", err)
+		}
+		var upper, lower, digits, symbols int
+		for _, r := range password {
+			switch {
+			case unicode.IsUpper(r):
+				upper++
+			case unicode.IsLower(r):
+				lower++
+			case unicode.IsDigit(r):
+				digits++
+			default:
+				symbols++
+			}
+		}
+		if upper < 2 || lower < 1 || digits < 3 || symbols < 2 {
+			t.Fatalf("Password %q does not satisfy the policy", password)
+		}
+	}
+}
+
+func TestGenerateExclusions(t *testing.T) {
+	policy := Policy{Length: 64, Charset: "abc01OlIxyz", ExcludeAmbiguous: true, Exclude: "xy"}
+	if got := string(policy.Alphabet()); got != "abcz" {
+		t.Fatalf("Expected alphabet %q, got %q", "abcz", got)
+	}
+	password, err := Generate(policy)
+	if err != nil {
+		t.Fatalf("Failed to generate password: %v", err)
+	}
+	if strings.Trim(password, "abcz") != "" {
+		t.Fatalf("Excluded characters in %q", password)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []Policy{
+		{Length: 0},
+		{Length: 8, Charset: "abc", Exclude: "abc"},
+		{Length: 8, MinDigits: -1},
+		{Length: 8, Charset: "abcdef", MinDigits: 1},
+		{Length: 8, ExcludeAmbiguous: true, Charset: "01", MinDigits: 1},
+		{Length: 4, MinUpper: 2, MinDigits: 2, MinSymbols: 1},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Fatalf("Expected policy %+v to be invalid", policy)
+		}
+		if _, err := Generate(policy); err == nil {
+			t.Fatalf("Generate should reject policy %+v", policy)
+		}
+	}
+
+	if err := (Policy{Length: 4, MinUpper: 1, MinLower: 1, MinDigits: 1, MinSymbols: 1}).Validate(); err != nil {
+		t.Fatalf("Expected a valid policy, got %v", err)
+	}
+}
+
+func TestGenerateDenseMinimums(t *testing.T) {
+	// Policies whose minimums (nearly) fill the length are practically never met by drawing
+	// every character from the whole alphabet.
+	policies := []Policy{
+		{Length: 20, MinDigits: 16},
+		{Length: 5, Charset: "abcdefghijklmnopqrstuvwxyz0", MinDigits: 5},
+		{Length: 12, MinUpper: 3, MinLower: 3, MinDigits: 3, MinSymbols: 3},
+	}
+	for _, policy := range policies {
+		password, err := Generate(policy)
+		if err != nil {
+			t.Fatalf("Failed to generate a password for %+v: %v", policy, err)
+		}
+		var digits int
+		for _, r := range password {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if len([]rune(password)) != policy.Length || digits < policy.MinDigits {
+			t.Fatalf("Password %q does not satisfy %+v", password, policy)
+		}
+	}
+}
+
+func TestGenerateUniform(t *testing.T) {
+	// With two characters and at least one of each, "ab" and "ba" must be equally likely.
+	policy := Policy{Length: 2, Charset: "aB", MinUpper: 1, MinLower: 1}
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		password, err := Generate(policy)
+		if err != nil {
+			t.Fatalf("Failed to generate password: %v", err)
+		}
+		counts[password]++
+	}
+	if len(counts) != 2 || counts["aB"] < 850 || counts["Ba"] < 850 {
+		t.Fatalf("Expected aB and Ba about equally often, got %v", counts)
+	}
+}
+
+func TestAlphabetDeduplicates(t *testing.T) {
+	policy := Policy{Length: 8, Charset: "aaaaaaaab"}
+	if got := string(policy.Alphabet()); got != "ab" {
+		t.Fatalf("Expected alphabet %q, got %q", "ab", got)
+	}
+}
+
+func TestGenerateUnicode(t *testing.T) {
+	policy := Policy{Length: 32, Charset: "áéíóúñü€"}
+	password, err := Generate(policy)
+	if err != nil {
+		t.Fatalf("Failed to generate password: %v", err)
+	}
+	if !utf8.ValidString(password) || utf8.RuneCountInString(password) != 32 {
+		t.Fatalf("Expected 32 valid runes, got %q", password)
+	}
+	for _, r := range password {
+		if !strings.ContainsRune(policy.Charset, r) {
+			t.Fatalf("Unexpected character %q in %q", r, password)
+		}
+	}
+}
+
+func TestValidateCharacters(t *testing.T) {
+	for _, policy := range []Policy{
+		{Length: 8, Charset: "ab\xffcd"},
+		{Length: 8, Charset: "ab cd"},
+		{Length: 8, Charset: "ab\tcd"},
+		{Length: 8, Exclude: "\x00"},
+	} {
+		if err := policy.Validate(); err == nil {
+			t.Fatalf("Expected charset %q, exclude %q to be rejected", policy.Charset, policy.Exclude)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, name := range PresetNames() {
+		charset, err := Preset(name)
+		if err != nil {
+			t.Fatalf("Failed to get preset %q: %v", name, err)
+		}
+		policy := Policy{Length: 12, Charset: charset}
+		if len(policy.Alphabet()) != len(charset) {
+			t.Fatalf("Preset %q contains duplicate characters", name)
+		}
+		if _, err := Generate(policy); err != nil {
+			t.Fatalf("Failed to generate with preset %q: %v", name, err)
+		}
+	}
+
+	if _, err := Preset("nonexistent"); err == nil || !strings.Contains(err.Error(), "base64url") {
+		t.Fatalf("Expected an error listing the presets, got %v", err)
+	}
+}
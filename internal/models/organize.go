@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CleanFolder normalizes a folder path: surrounding and repeated slashes are dropped, so
+// "/work//aws/" becomes "work/aws", and "" or "/" is the top level. Returns an error if a
+// segment is "." or "..".
+func CleanFolder(path string) (string, error) {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimSpace(segment)
+		switch segment {
+		case "":
+			continue
+		case ".", "..":
+			return "", fmt.Errorf("invalid folder %q: %q is not allowed", path, segment)
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// CleanTag trims tag and checks that it is not empty and contains no spaces or commas.
+func CleanTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", fmt.Errorf("tags cannot be empty")
+	}
+	if strings.ContainsFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+		return "", fmt.Errorf("invalid tag %q: tags cannot contain spaces or commas", tag)
+	}
+	return tag, nil
+}
+
+// InFolder reports whether the entry is in folder or one of its subfolders. Every entry is
+// in the top level folder "".
+func (e PasswordEntry) InFolder(folder string) bool {
+	return folder == "" || e.Folder == folder || strings.HasPrefix(e.Folder, folder+"/")
+}
+
+// HasTag reports whether the entry has tag, compared case-insensitively.
+func (e PasswordEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds tag to the entry and reports whether it was missing.
+func (e *PasswordEntry) AddTag(tag string) bool {
+	if e.HasTag(tag) {
+		return false
+	}
+	e.Tags = append(e.Tags, tag)
+	return true
+}
+
+// RemoveTag removes tag from the entry and reports whether it was present.
+func (e *PasswordEntry) RemoveTag(tag string) bool {
+	for i, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			e.Tags = append(e.Tags[:i], e.Tags[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects entries by folder, tags, type and name. The zero Filter matches every entry.
+type Filter struct {
+	Folder string    // entries in this folder or its subfolders
+	Tags   []string  // entries that have all of these tags
+	Type   EntryType // entries of this type
+	Name   string    // entries whose name contains this, ignoring case
+}
+
+// IsSet reports whether the filter restricts the entries at all.
+func (f Filter) IsSet() bool {
+	return f.Folder != "" || len(f.Tags) > 0 || f.Type != "" || f.Name != ""
+}
+
+// Match reports whether entry passes the filter.
+func (f Filter) Match(entry PasswordEntry) bool {
+	if !entry.InFolder(f.Folder) {
+		return false
+	}
+	if f.Type != "" && entry.Kind() != f.Type {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(entry.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !entry.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the entries that pass the filter, in their original order.
+func (f Filter) Apply(entries []PasswordEntry) []PasswordEntry {
+	var matches []PasswordEntry
+	for _, entry := range entries {
+		if f.Match(entry) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
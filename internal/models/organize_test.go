@@ -0,0 +1,90 @@
+package models
+
+import "testing"
+
+func TestCleanFolder(t *testing.T) {
+	tests := []struct {
+		path   string
+		folder string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"work", "work"},
+		{"/work//aws/prod/", "work/aws/prod"},
+		{" work / aws ", "work/aws"},
+	}
+	for _, tt := range tests {
+		got, err := CleanFolder(tt.path)
+		if err != nil {
+			t.Fatalf("Failed to clean %q: %v", tt.path, err)
+		}
+		if got != tt.folder {
+			t.Fatalf("Expected %q for %q, got %q", tt.folder, tt.path, got)
+		}
+	}
+
+	for _, invalid := range []string{"work/../home", "./work"} {
+		if _, err := CleanFolder(invalid); err == nil {
+			t.Fatalf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCleanTag(t *testing.T) {
+	if tag, err := CleanTag("  prod "); err != nil || tag != "prod" {
+		t.Fatalf("Expected prod, got %q (%v)", tag, err)
+	}
+	for _, invalid := range []string{"", "  ", "two words", "a,b"} {
+		if _, err := CleanTag(invalid); err == nil {
+			t.Fatalf("Expected tag %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	var entry PasswordEntry
+	if !entry.AddTag("Prod") || entry.AddTag("prod") {
+		t.Fatal("Expected a tag to be added once, ignoring case")
+	}
+	entry.AddTag("aws")
+	if !entry.HasTag("PROD") {
+		t.Fatal("Expected HasTag to ignore case")
+	}
+	if !entry.RemoveTag("prod") || entry.RemoveTag("prod") {
+		t.Fatal("Expected a tag to be removed once")
+	}
+	if len(entry.Tags) != 1 || entry.Tags[0] != "aws" {
+		t.Fatalf("Expected only aws to remain, got %v", entry.Tags)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entries := []PasswordEntry{
+		{ID: "1", Folder: "work/aws/prod", Tags: []string{"aws", "prod"}},
+		{ID: "2", Folder: "work/aws", Tags: []string{"aws"}},
+		{ID: "3", Folder: "work/awsome"},
+		{ID: "4", Folder: "personal", Tags: []string{"prod"}},
+		{ID: "5"},
+	}
+
+	tests := []struct {
+		filter Filter
+		ids    string
+	}{
+		{Filter{}, "12345"},
+		{Filter{Folder: "work"}, "123"},
+		{Filter{Folder: "work/aws"}, "12"},
+		{Filter{Tags: []string{"prod"}}, "14"},
+		{Filter{Tags: []string{"AWS", "prod"}}, "1"},
+		{Filter{Folder: "personal", Tags: []string{"aws"}}, ""},
+	}
+	for _, tt := range tests {
+		ids := ""
+		for _, entry := range tt.filter.Apply(entries) {
+			ids += entry.ID
+		}
+		if ids != tt.ids {
+			t.Fatalf("Expected entries %q for %+v, got %q", tt.ids, tt.filter, ids)
+		}
+	}
+}
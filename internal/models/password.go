@@ -1,14 +1,55 @@
 package models
 
-import "time"
+import (
+	"crypto/rand"
+	"fmt"
+	"mpass/internal/totp"
+	"strings"
+	"time"
+)
 
 // PasswordEntry represents a single password entry
 type PasswordEntry struct {
+	ID        string    `json:"id"`
 	Username  string    `json:"username"`
 	URL       string    `json:"url"`
 	Password  string    `json:"password"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// Type says what the entry stores; entries saved without one are logins.
+	Type EntryType `json:"type,omitempty"`
+	// Name labels entries that are not identified by a username and URL, such as cards.
+	Name string `json:"name,omitempty"`
+	// Folder is a slash separated path such as "work/aws/prod"; empty is the top level.
+	Folder string `json:"folder,omitempty"`
+	// Tags label the entry across folders.
+	Tags []string `json:"tags,omitempty"`
+	// Notes is free-form, possibly multi-line text.
+	Notes string `json:"notes,omitempty"`
+	// Fields holds extra named values such as security answers or recovery codes, in the
+	// order they were added.
+	Fields []CustomField `json:"fields,omitempty"`
+	// OTP is the two-factor secret used to compute one-time codes for the entry.
+	OTP *totp.Key `json:"otp,omitempty"`
+	// History holds the passwords the entry used before, most recently replaced first.
+	History []PreviousPassword `json:"history,omitempty"`
+}
+
+// CustomField is an extra named value stored with an entry. Concealed fields are treated
+// like passwords and only ever copied, never printed.
+type CustomField struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Concealed bool   `json:"concealed,omitempty"`
+}
+
+// MaxHistory is the number of previous passwords kept per entry.
+const MaxHistory = 10
+
+// PreviousPassword is a password an entry used before, with the time it was replaced.
+type PreviousPassword struct {
+	Password   string    `json:"password"`
+	ReplacedAt time.Time `json:"replaced_at"`
 }
 
 // Vault represents the encrypted storage container
@@ -16,3 +57,95 @@ type Vault struct {
 	Entries []PasswordEntry `json:"entries"`
 	Salt    []byte          `json:"salt"`
 }
+
+// NewID returns a random (version 4) UUID to identify a password entry.
+// Returns an error if the system random number generator fails.
+func NewID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate entry ID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Title returns the name of the entry if it has one, otherwise username@URL.
+func (e PasswordEntry) Title() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Username + "@" + e.URL
+}
+
+// ShortID returns the first eight characters of the entry ID, enough to tell entries apart in listings.
+func (e PasswordEntry) ShortID() string {
+	if len(e.ID) < 8 {
+		return e.ID
+	}
+	return e.ID[:8]
+}
+
+// RecordPrevious adds password to the front of the history as replaced at the given time,
+// dropping the oldest passwords beyond MaxHistory.
+func (e *PasswordEntry) RecordPrevious(password string, replacedAt time.Time) {
+	history := append([]PreviousPassword{{Password: password, ReplacedAt: replacedAt}}, e.History...)
+	if len(history) > MaxHistory {
+		history = history[:MaxHistory]
+	}
+	e.History = history
+}
+
+// Previous returns the password used n changes ago, where 1 is the password replaced last.
+// Returns an error if the history does not go back that far.
+func (e PasswordEntry) Previous(n int) (PreviousPassword, error) {
+	if n < 1 {
+		return PreviousPassword{}, fmt.Errorf("previous password number must be at least 1, got %d", n)
+	}
+	if n > len(e.History) {
+		return PreviousPassword{}, fmt.Errorf("entry has %d previous passwords, cannot go back %d", len(e.History), n)
+	}
+	return e.History[n-1], nil
+}
+
+// PasswordSetAt returns when the current password was set: when the last one was replaced,
+// or when the entry was created if the password was never changed.
+func (e PasswordEntry) PasswordSetAt() time.Time {
+	if len(e.History) > 0 {
+		return e.History[0].ReplacedAt
+	}
+	return e.CreatedAt
+}
+
+// Field returns the custom field with the given name, compared case-insensitively.
+func (e PasswordEntry) Field(name string) (CustomField, bool) {
+	for _, field := range e.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return CustomField{}, false
+}
+
+// SetField stores field, replacing the field with the same name in place or adding it
+// at the end.
+func (e *PasswordEntry) SetField(field CustomField) {
+	for i := range e.Fields {
+		if strings.EqualFold(e.Fields[i].Name, field.Name) {
+			e.Fields[i] = field
+			return
+		}
+	}
+	e.Fields = append(e.Fields, field)
+}
+
+// RemoveField deletes the custom field with the given name and reports whether it existed.
+func (e *PasswordEntry) RemoveField(name string) bool {
+	for i := range e.Fields {
+		if strings.EqualFold(e.Fields[i].Name, name) {
+			e.Fields = append(e.Fields[:i], e.Fields[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -89,3 +91,95 @@ func TestPasswordEntryDefaults(t *testing.T) {
 		t.Fatal("Expected zero UpdatedAt")
 	}
 }
+
+func TestNewID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := NewID()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: %v", err)
+		}
+		if !pattern.MatchString(id) {
+			t.Fatalf("ID %q is not a version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID generated: %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestShortID(t *testing.T) {
+	entry := PasswordEntry{ID: "0123abcd-0000-4000-8000-000000000000"}
+	if entry.ShortID() != "0123abcd" {
+		t.Fatalf("Expected short ID '0123abcd', got '%s'", entry.ShortID())
+	}
+
+	if (PasswordEntry{}).ShortID() != "" {
+		t.Fatal("Entry without ID should have an empty short ID")
+	}
+}
+
+func TestRecordPrevious(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := PasswordEntry{Password: "current", CreatedAt: created}
+	if !entry.PasswordSetAt().Equal(created) {
+		t.Fatalf("Expected an unchanged password to date from creation, got %s", entry.PasswordSetAt())
+	}
+
+	for i := 0; i < MaxHistory+3; i++ {
+		entry.RecordPrevious(fmt.Sprintf("old-%d", i), created.AddDate(0, 0, i+1))
+	}
+	if len(entry.History) != MaxHistory {
+		t.Fatalf("Expected history to be capped at %d, got %d", MaxHistory, len(entry.History))
+	}
+
+	last := MaxHistory + 2
+	previous, err := entry.Previous(1)
+	if err != nil {
+		t.Fatalf("Failed to get previous password: %v", err)
+	}
+	if previous.Password != fmt.Sprintf("old-%d", last) {
+		t.Fatalf("Expected the last replaced password first, got %s", previous.Password)
+	}
+	if !entry.PasswordSetAt().Equal(created.AddDate(0, 0, last+1)) {
+		t.Fatalf("Expected the password to date from its last change, got %s", entry.PasswordSetAt())
+	}
+
+	oldest, _ := entry.Previous(MaxHistory)
+	if oldest.Password != "old-3" {
+		t.Fatalf("Expected the oldest kept password to be old-3, got %s", oldest.Password)
+	}
+	for _, n := range []int{0, -1, MaxHistory + 1} {
+		if _, err := entry.Previous(n); err == nil {
+			t.Fatalf("Expected Previous(%d) to fail", n)
+		}
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	var entry PasswordEntry
+	entry.SetField(CustomField{Name: "PIN", Value: "1234", Concealed: true})
+	entry.SetField(CustomField{Name: "Account", Value: "42"})
+	entry.SetField(CustomField{Name: "pin", Value: "9876", Concealed: true})
+
+	if len(entry.Fields) != 2 || entry.Fields[0].Name != "pin" || entry.Fields[1].Name != "Account" {
+		t.Fatalf("Expected a replaced field to keep its position, got %+v", entry.Fields)
+	}
+	field, ok := entry.Field("PIN")
+	if !ok || field.Value != "9876" || !field.Concealed {
+		t.Fatalf("Expected the updated PIN field, got %+v", field)
+	}
+
+	if !entry.RemoveField("account") {
+		t.Fatal("Expected the Account field to be removed")
+	}
+	if entry.RemoveField("account") {
+		t.Fatal("Removing a missing field should report false")
+	}
+	if _, ok := entry.Field("Account"); ok || len(entry.Fields) != 1 {
+		t.Fatalf("Expected only the PIN field to remain, got %+v", entry.Fields)
+	}
+}
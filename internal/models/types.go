@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EntryType identifies what an entry stores and which fields it uses.
+type EntryType string
+
+const (
+	TypeLogin    EntryType = "login"     // website or service login
+	TypeNote     EntryType = "note"      // secure note kept in Notes
+	TypeCard     EntryType = "card"      // payment card
+	TypeIdentity EntryType = "identity"  // personal details
+	TypeAPIToken EntryType = "api-token" // API token with its key ID
+	TypeSSHKey   EntryType = "ssh-key"   // SSH key pair
+)
+
+// What get copies to the clipboard for a type, besides the name of one of its fields.
+const (
+	CopyPassword = "password"
+	CopyNotes    = "notes"
+)
+
+// FieldSpec describes a custom field that entries of a type carry.
+type FieldSpec struct {
+	Name      string
+	Concealed bool
+	Multiline bool // the value usually spans several lines, such as a private key
+}
+
+// Schema describes the fields an entry type uses. The username, URL and password keep their
+// place in PasswordEntry under the labels given here; everything else is stored in custom
+// fields named after Fields, so it can be copied with get --field like any other field.
+type Schema struct {
+	Type     EntryType
+	Label    string      // human readable name, such as "payment card"
+	Username string      // label of the username, "" if the type has none
+	URL      string      // label of the URL, "" if the type has none
+	Password string      // label of the password, "" if the type has none
+	Fields   []FieldSpec // typed fields, in the order they are asked for
+	Copy     string      // what get copies: CopyPassword, CopyNotes, a field name, or "" for nothing
+}
+
+// schemas lists the entry types in the order they are shown to users.
+var schemas = []Schema{
+	{
+		Type:     TypeLogin,
+		Label:    "login",
+		Username: "Username",
+		URL:      "URL",
+		Password: "Password",
+		Copy:     CopyPassword,
+	},
+	{
+		Type:  TypeNote,
+		Label: "secure note",
+		Copy:  CopyNotes,
+	},
+	{
+		Type:  TypeCard,
+		Label: "payment card",
+		Fields: []FieldSpec{
+			{Name: "Cardholder"},
+			{Name: "Number", Concealed: true},
+			{Name: "Expiry"},
+			{Name: "CVV", Concealed: true},
+			{Name: "PIN", Concealed: true},
+		},
+		Copy: "Number",
+	},
+	{
+		Type:  TypeIdentity,
+		Label: "identity",
+		Fields: []FieldSpec{
+			{Name: "Full name"},
+			{Name: "Email"},
+			{Name: "Phone"},
+			{Name: "Address", Multiline: true},
+			{Name: "Birth date"},
+			{Name: "ID number", Concealed: true},
+		},
+	},
+	{
+		Type:     TypeAPIToken,
+		Label:    "API token",
+		Username: "Key ID",
+		URL:      "URL",
+		Password: "Token",
+		Copy:     CopyPassword,
+	},
+	{
+		Type:     TypeSSHKey,
+		Label:    "SSH key",
+		Username: "User",
+		URL:      "Host",
+		Password: "Passphrase",
+		Fields: []FieldSpec{
+			{Name: "Public key"},
+			{Name: "Private key", Concealed: true, Multiline: true},
+		},
+		Copy: "Private key",
+	},
+}
+
+// Types returns the known entry types.
+func Types() []EntryType {
+	types := make([]EntryType, len(schemas))
+	for i, schema := range schemas {
+		types[i] = schema.Type
+	}
+	return types
+}
+
+// ParseType returns the entry type named s, ignoring case. An empty s is a login.
+func ParseType(s string) (EntryType, error) {
+	if s == "" {
+		return TypeLogin, nil
+	}
+	for _, schema := range schemas {
+		if strings.EqualFold(string(schema.Type), s) {
+			return schema.Type, nil
+		}
+	}
+	names := make([]string, len(schemas))
+	for i, schema := range schemas {
+		names[i] = string(schema.Type)
+	}
+	return "", fmt.Errorf("unknown entry type %q (available: %s)", s, strings.Join(names, ", "))
+}
+
+// Schema returns the schema of the type. Types this version does not know, for example from
+// a vault written by a newer one, are treated like logins.
+func (t EntryType) Schema() Schema {
+	for _, schema := range schemas {
+		if schema.Type == t {
+			return schema
+		}
+	}
+	schema := schemas[0]
+	schema.Type, schema.Label = t, string(t)
+	return schema
+}
+
+// FieldSpec returns the typed field with the given name, compared case-insensitively.
+func (s Schema) FieldSpec(name string) (FieldSpec, bool) {
+	for _, spec := range s.Fields {
+		if strings.EqualFold(spec.Name, name) {
+			return spec, true
+		}
+	}
+	return FieldSpec{}, false
+}
+
+// Kind returns the type of the entry; entries saved without a type are logins.
+func (e PasswordEntry) Kind() EntryType {
+	if e.Type == "" {
+		return TypeLogin
+	}
+	return e.Type
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		name      string
+		entryType EntryType
+	}{
+		{"", TypeLogin},
+		{"login", TypeLogin},
+		{"Card", TypeCard},
+		{"ssh-key", TypeSSHKey},
+		{"API-TOKEN", TypeAPIToken},
+	}
+	for _, tt := range tests {
+		got, err := ParseType(tt.name)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.name, err)
+		}
+		if got != tt.entryType {
+			t.Fatalf("Expected %s for %q, got %s", tt.entryType, tt.name, got)
+		}
+	}
+	if _, err := ParseType("wallet"); err == nil {
+		t.Fatal("Expected an unknown type to be rejected")
+	}
+	if len(Types()) != 6 {
+		t.Fatalf("Expected 6 entry types, got %v", Types())
+	}
+}
+
+func TestEntryWithoutTypeIsLogin(t *testing.T) {
+	var entry PasswordEntry
+	if err := json.Unmarshal([]byte(`{"id": "1", "username": "rob", "url": "github.com", "password": "secret"}`), &entry); err != nil {
+		t.Fatalf("Failed to decode entry: %v", err)
+	}
+	if entry.Kind() != TypeLogin {
+		t.Fatalf("Expected an entry without type to be a login, got %q", entry.Kind())
+	}
+	if entry.Title() != "rob@github.com" {
+		t.Fatalf("Expected the title rob@github.com, got %q", entry.Title())
+	}
+}
+
+func TestSchema(t *testing.T) {
+	card := TypeCard.Schema()
+	if card.Password != "" || card.Copy != "Number" {
+		t.Fatalf("Unexpected card schema: %+v", card)
+	}
+	spec, ok := card.FieldSpec("cvv")
+	if !ok || !spec.Concealed || spec.Name != "CVV" {
+		t.Fatalf("Expected a concealed CVV field, got %+v", spec)
+	}
+	if _, ok := card.FieldSpec("Email"); ok {
+		t.Fatal("Cards should not have an Email field")
+	}
+
+	future := EntryType("passkey").Schema()
+	if future.Type != "passkey" || future.Copy != CopyPassword {
+		t.Fatalf("Expected unknown types to be treated like logins, got %+v", future)
+	}
+}
+
+func TestFilterTypeAndName(t *testing.T) {
+	entries := []PasswordEntry{
+		{ID: "1", Username: "rob", URL: "github.com"},
+		{ID: "2", Type: TypeCard, Name: "Visa"},
+		{ID: "3", Type: TypeLogin, Name: "Work Visa portal"},
+	}
+	tests := []struct {
+		filter Filter
+		ids    string
+	}{
+		{Filter{Type: TypeLogin}, "13"},
+		{Filter{Type: TypeCard}, "2"},
+		{Filter{Name: "visa"}, "23"},
+		{Filter{Type: TypeLogin, Name: "visa"}, "3"},
+	}
+	for _, tt := range tests {
+		ids := ""
+		for _, entry := range tt.filter.Apply(entries) {
+			ids += entry.ID
+		}
+		if ids != tt.ids {
+			t.Fatalf("Expected entries %q for %+v, got %q", tt.ids, tt.filter, ids)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package passphrase generates diceware style passphrases from the EFF large wordlist.
+package passphrase
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// effLargeWordlist is the EFF large wordlist, one "dice rolls<TAB>word" line per word:
+// https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Wordlist returns the words of the EFF large wordlist in dice roll order.
+var Wordlist = sync.OnceValue(func() []string {
+	words := make([]string, 0, 7776)
+	scanner := bufio.NewScanner(strings.NewReader(effLargeWordlist))
+	for scanner.Scan() {
+		if _, word, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// Options controls how a passphrase is built.
+type Options struct {
+	Words       int    // number of words
+	Separator   string // placed between words
+	Capitalize  bool   // upper case the first letter of every word
+	AppendDigit bool   // append a random digit to the passphrase
+}
+
+// Validate checks that the options describe a passphrase that can be generated.
+func (o Options) Validate() error {
+	if o.Words <= 0 {
+		return fmt.Errorf("the number of words must be greater than zero")
+	}
+	return nil
+}
+
+// Entropy returns the entropy in bits of a passphrase generated with o: log2 of the
+// wordlist size for every word, plus log2(10) for the appended digit. Capitalizing every
+// word and the separator are fixed choices and add nothing.
+func (o Options) Entropy() float64 {
+	bits := float64(o.Words) * math.Log2(float64(len(Wordlist())))
+	if o.AppendDigit {
+		bits += math.Log2(10)
+	}
+	return bits
+}
+
+// randomIndex returns a uniformly random integer in [0, n) from crypto/rand.
+func randomIndex(n int) (int, error) {
+	idx, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(idx.Int64()), nil
+}
+
+// Generate returns a new passphrase built with o, picking every word uniformly at random
+// from the EFF large wordlist with crypto/rand.
+func Generate(o Options) (string, error) {
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+
+	wordlist := Wordlist()
+	words := make([]string, o.Words)
+	for i := range words {
+		idx, err := randomIndex(len(wordlist))
+		if err != nil {
+			return "", fmt.Errorf("failed to pick a word: %w", err)
+		}
+		words[i] = wordlist[idx]
+		if o.Capitalize {
+			words[i] = capitalize(words[i])
+		}
+	}
+
+	passphrase := strings.Join(words, o.Separator)
+	if o.AppendDigit {
+		digit, err := randomIndex(10)
+		if err != nil {
+			return "", fmt.Errorf("failed to pick a digit: %w", err)
+		}
+		passphrase += fmt.Sprint(digit)
+	}
+	return passphrase, nil
+}
+
+// capitalize upper cases the first letter of word.
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + word[size:]
+}
@@ -0,0 +1,72 @@
+package passphrase
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestWordlist(t *testing.T) {
+	words := Wordlist()
+	if len(words) != 7776 {
+		t.Fatalf("Expected 7776 words, got %d", len(words))
+	}
+	if words[0] != "abacus" || words[len(words)-1] != "zoom" {
+		t.Fatalf("Unexpected first or last word: %q, %q", words[0], words[len(words)-1])
+	}
+
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		if seen[word] {
+			t.Fatalf("Duplicate word %q", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	passphrase, err := Generate(Options{Words: 6, Separator: "-"})
+	if err != nil {
+		t.Fatalf("Failed to generate passphrase: %v", err)
+	}
+
+	words := strings.Split(passphrase, "-")
+	if len(words) < 6 {
+		t.Fatalf("Expected 6 words, got %q", passphrase)
+	}
+}
+
+func TestGenerateCapitalizeAppendDigit(t *testing.T) {
+	passphrase, err := Generate(Options{Words: 4, Separator: " ", Capitalize: true, AppendDigit: true})
+	if err != nil {
+		t.Fatalf("Failed to generate passphrase: %v", err)
+	}
+
+	last := passphrase[len(passphrase)-1]
+	if last < '0' || last > '9' {
+		t.Fatalf("Expected a trailing digit, got %q", passphrase)
+	}
+	for _, word := range strings.Fields(passphrase[:len(passphrase)-1]) {
+		if !unicode.IsUpper(rune(word[0])) {
+			t.Fatalf("Expected capitalized words, got %q", passphrase)
+		}
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	if _, err := Generate(Options{Words: 0}); err == nil {
+		t.Fatal("Expected an error for zero words")
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	perWord := math.Log2(7776)
+	if got := (Options{Words: 6}).Entropy(); math.Abs(got-6*perWord) > 1e-9 {
+		t.Fatalf("Expected %f bits, got %f", 6*perWord, got)
+	}
+	got := (Options{Words: 6, Capitalize: true, AppendDigit: true}).Entropy()
+	if want := 6*perWord + math.Log2(10); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("Expected %f bits, got %f", want, got)
+	}
+}
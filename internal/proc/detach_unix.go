@@ -0,0 +1,10 @@
+//go:build !windows
+
+package proc
+
+import "syscall"
+
+// detachAttr puts the child in a new session so it is not killed with the terminal.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package proc
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// detachAttr starts the child without a console, in its own process group.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: windows.DETACHED_PROCESS | windows.CREATE_NEW_PROCESS_GROUP,
+		HideWindow:    true,
+	}
+}
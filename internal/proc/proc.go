@@ -0,0 +1,138 @@
+// Package proc starts background helper processes that outlive the mpass command which
+// started them, such as the unlock agent.
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reportEnv tells a process started by StartSelfAndWait which file descriptor to Report on.
+const reportEnv = "MPASS_REPORT_FD"
+
+// readyMessage is what Report writes once the process is ready.
+const readyMessage = "ready"
+
+// StartSelf starts the running mpass executable with args as a detached background process:
+// it gets its own session, no terminal and, if stdin is not nil, stdin as its standard input.
+// stdin is meant for small payloads such as a digest or a token.
+// It returns as soon as the process has been started.
+func StartSelf(stdin []byte, args ...string) error {
+	cmd, err := selfCommand(stdin, args...)
+	if err != nil {
+		return err
+	}
+	if cmd.Stdin != nil {
+		defer cmd.Stdin.(*os.File).Close()
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start background process: %w", err)
+	}
+
+	// Reap the child should it exit while this process is still running.
+	go cmd.Wait()
+	return nil
+}
+
+// StartSelfAndWait starts a background process like StartSelf, but only returns once the
+// process has called Report. It returns the error the process reported, or an error if the
+// process exits or does not report within timeout, in which case it is killed.
+func StartSelfAndWait(stdin []byte, timeout time.Duration, args ...string) error {
+	cmd, err := selfCommand(stdin, args...)
+	if err != nil {
+		return err
+	}
+	if cmd.Stdin != nil {
+		defer cmd.Stdin.(*os.File).Close()
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	defer r.Close()
+	cmd.ExtraFiles = []*os.File{w}
+	cmd.Env = append(os.Environ(), reportEnv+"=3")
+	err = cmd.Start()
+	w.Close() // the child holds the only write end now, so its exit ends the read below
+	if err != nil {
+		return fmt.Errorf("failed to start background process: %w", err)
+	}
+	go cmd.Wait()
+
+	r.SetReadDeadline(time.Now().Add(timeout))
+	report, err := io.ReadAll(r)
+	message := strings.TrimSpace(string(report))
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		cmd.Process.Kill()
+		return fmt.Errorf("background process did not start within %s", timeout)
+	case message == readyMessage:
+		return nil
+	case message == "":
+		return errors.New("background process exited before it was ready")
+	default:
+		return errors.New(message)
+	}
+}
+
+// selfCommand prepares the command StartSelf and StartSelfAndWait run.
+func selfCommand(stdin []byte, args ...string) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate mpass executable: %w", err)
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.SysProcAttr = detachAttr()
+	if stdin != nil {
+		// Fill a pipe up front instead of letting exec copy stdin from a goroutine, which
+		// may not have run yet when this process exits. stdin must fit in the pipe buffer.
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pipe: %w", err)
+		}
+		_, err = w.Write(stdin)
+		w.Close()
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to write to pipe: %w", err)
+		}
+		cmd.Stdin = r
+	}
+	return cmd, nil
+}
+
+// reportOnce makes sure only the first Report reaches the parent.
+var reportOnce sync.Once
+
+// Report tells the process that started this one with StartSelfAndWait that it is ready,
+// when err is nil, or that it failed with err. Only the first call has an effect, and none
+// does in a process that was not started by StartSelfAndWait.
+func Report(err error) {
+	reportOnce.Do(func() {
+		fd, convErr := strconv.Atoi(os.Getenv(reportEnv))
+		if convErr != nil {
+			return
+		}
+		os.Unsetenv(reportEnv)
+		f := os.NewFile(uintptr(fd), "report")
+		if f == nil {
+			return
+		}
+		defer f.Close()
+
+		message := readyMessage
+		if err != nil {
+			message = err.Error()
+		}
+		f.WriteString(message + "\n")
+	})
+}
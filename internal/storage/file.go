@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mpass/internal/crypto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBackups is the number of previous vault generations kept next to the vault unless
+// SetBackups says otherwise.
+const DefaultBackups = 5
+
+// Backup describes a previous generation of the vault file kept as vault.enc.N,
+// where generation 1 is the most recent one.
+type Backup struct {
+	Generation int
+	Path       string
+	ModTime    time.Time
+	Size       int64
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as path, flushes it
+// to disk and renames it over path once it is complete. If beforeRename is not nil it is called
+// with the temporary file path right before the rename; an error aborts the write and leaves
+// path untouched.
+func writeFileAtomic(path string, data []byte, beforeRename func(tmpPath string) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary vault file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename has succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary vault file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to flush temporary vault file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary vault file: %w", err)
+	}
+
+	if beforeRename != nil {
+		if err := beforeRename(tmpPath); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace vault file: %w", err)
+	}
+
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a directory entry change (such as a rename) to disk.
+// Errors are ignored because not every platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// backupPath returns the path of the given backup generation of the vault.
+func (v *VaultManager) backupPath(generation int) string {
+	return v.vaultPath + "." + strconv.Itoa(generation)
+}
+
+// rotateBackups shifts vault.enc.1..N-1 to vault.enc.2..N, dropping the oldest generation,
+// and preserves the current vault file as vault.enc.1. Nothing happens when backups are
+// disabled or the vault does not exist yet.
+func (v *VaultManager) rotateBackups() error {
+	if v.backups <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(v.vaultPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err := os.Remove(v.backupPath(v.backups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove oldest backup: %w", err)
+	}
+	for i := v.backups - 1; i >= 1; i-- {
+		err := os.Rename(v.backupPath(i), v.backupPath(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate backup %d: %w", i, err)
+		}
+	}
+
+	// A hard link keeps the current generation without copying it; the rename that
+	// follows points the vault path at the new file and leaves the link intact.
+	if err := os.Link(v.vaultPath, v.backupPath(1)); err == nil {
+		return nil
+	}
+	if err := copyFile(v.vaultPath, v.backupPath(1)); err != nil {
+		return fmt.Errorf("failed to back up vault: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst with owner-only permissions and flushes dst to disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeVaultFile atomically replaces the vault file with data, rotating the backups first.
+// verify, if not nil, is called with the path of the fully written temporary file and can
+// abort the write before anything on disk is changed.
+func (v *VaultManager) writeVaultFile(data []byte, verify func(tmpPath string) error) error {
+	return writeFileAtomic(v.vaultPath, data, func(tmpPath string) error {
+		if verify != nil {
+			if err := verify(tmpPath); err != nil {
+				return err
+			}
+		}
+		return v.rotateBackups()
+	})
+}
+
+// writeRekeyedVault atomically replaces the vault file with data, the contents of vault after
+// rekey gave it a new salt and key, and then re-encrypts the backups with that key. Unlike
+// writeVaultFile it does not rotate the previous file into the backups: it holds the same
+// entries under the old key, which is exactly what must not be left on disk. verify works as
+// for writeVaultFile. previous is the vault as it was unlocked before rekey, and oldPassword
+// the master password that opened it.
+func (v *VaultManager) writeRekeyedVault(data []byte, vault, previous *unlockedVault, oldPassword string, verify func(tmpPath string) error) error {
+	if err := v.ensureVaultDir(); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	if err := writeFileAtomic(v.vaultPath, data, verify); err != nil {
+		return err
+	}
+	if v.key != nil {
+		v.key = vault.key
+	}
+	return v.reencryptBackups(vault, previous, oldPassword)
+}
+
+// reencryptBackups rewrites every backup generation with the header and key of vault. Backups
+// sharing the header of previous are opened with its key, others with a key derived from
+// oldPassword. Backups that cannot be opened that way, such as generations from before an
+// earlier master password change, are removed rather than left readable with an old password.
+func (v *VaultManager) reencryptBackups(vault, previous *unlockedVault, oldPassword string) error {
+	backups, err := v.Backups()
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	for _, backup := range backups {
+		data, err := v.reencryptBackup(backup.Path, vault, previous, oldPassword)
+		if err == nil {
+			err = writeFileAtomic(backup.Path, data, nil)
+		}
+		if err != nil {
+			if err := os.Remove(backup.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove backup %d, which still opens with the old key: %w", backup.Generation, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reencryptBackup decrypts the backup at path as described for reencryptBackups and returns
+// it encrypted with the header and key of vault.
+func (v *VaultManager) reencryptBackup(path string, vault, previous *unlockedVault, oldPassword string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	header, ciphertext, err := parseVaultFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key := previous.key
+	if !bytes.Equal(header.Salt, previous.header.Salt) || header.KDF != previous.header.KDF {
+		if key, err = crypto.DeriveKeyWithParams(oldPassword, header.Salt, header.KDF); err != nil {
+			return nil, err
+		}
+	}
+	plaintext, err := crypto.Decrypt(ciphertext, key)
+	if err != nil {
+		return nil, err
+	}
+	return sealVault(plaintext, vault.header, vault.key)
+}
+
+// Backups returns the backup generations currently kept next to the vault,
+// ordered from the most recent (generation 1) to the oldest.
+func (v *VaultManager) Backups() ([]Backup, error) {
+	matches, err := filepath.Glob(v.vaultPath + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, path := range matches {
+		generation, err := strconv.Atoi(strings.TrimPrefix(path, v.vaultPath+"."))
+		if err != nil || generation <= 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		backups = append(backups, Backup{
+			Generation: generation,
+			Path:       path,
+			ModTime:    info.ModTime(),
+			Size:       info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Generation < backups[j].Generation
+	})
+	return backups, nil
+}
+
+// ErrBackupsDisabled is returned by Restore when backups are disabled, since the vault being
+// replaced could not be kept.
+var ErrBackupsDisabled = errors.New("backups are disabled, so the current vault would be lost; set backups in the config file to restore")
+
+// Restore replaces the vault with the given backup generation. The vault being replaced
+// is rotated into the backups like any other save, so a restore can itself be undone.
+// Returns ErrBackupsDisabled if backups are disabled, or an error if the backup does not
+// exist or is not a valid vault file.
+func (v *VaultManager) Restore(generation int) error {
+	if v.backups <= 0 {
+		return ErrBackupsDisabled
+	}
+	return v.withLock(func() error {
+		data, err := os.ReadFile(v.backupPath(generation))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("backup %d does not exist", generation)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup %d: %w", generation, err)
+		}
+
+		if _, _, err := parseVaultFile(data); err != nil {
+			return fmt.Errorf("backup %d is not a valid vault: %w", generation, err)
+		}
+
+		return v.writeVaultFile(data, nil)
+	})
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"errors"
+	"mpass/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "vault.enc")
+
+	if err := writeFileAtomic(path, []byte("first"), nil); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "first" {
+		t.Fatalf("Expected 'first', got '%s'", data)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected permissions 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomicVerifyFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "vault.enc")
+
+	if err := os.WriteFile(path, []byte("original"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	err := writeFileAtomic(path, []byte("replacement"), func(tmpPath string) error {
+		data, _ := os.ReadFile(tmpPath)
+		if string(data) != "replacement" {
+			t.Fatalf("Temporary file should contain the new data, got '%s'", data)
+		}
+		return errors.New("verification failed")
+	})
+	if err == nil {
+		t.Fatal("Verification error should abort the write")
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "original" {
+		t.Fatalf("Original file should be untouched, got '%s'", data)
+	}
+
+	files, _ := os.ReadDir(tempDir)
+	if len(files) != 1 {
+		t.Fatalf("Temporary file should be removed, found %d files", len(files))
+	}
+}
+
+func TestSaveKeepsRollingBackups(t *testing.T) {
+	vault, _ := createTestVault(t)
+	vault.backups = 2
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	for _, username := range []string{"user1", "user2", "user3", "user4"} {
+		if err := vault.AddEntry(models.PasswordEntry{Username: username}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	backups, err := vault.Backups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(backups))
+	}
+	if backups[0].Generation != 1 || backups[1].Generation != 2 {
+		t.Fatalf("Backups should be ordered by generation, got %d and %d", backups[0].Generation, backups[1].Generation)
+	}
+
+	// Generation 1 is the vault before the last save, generation 2 the one before that
+	for i, expected := range []int{3, 2} {
+		backup := &VaultManager{vaultPath: backups[i].Path, kdf: testKDFParams}
+		entries, err := backup.GetAllEntries(masterPassword)
+		if err != nil {
+			t.Fatalf("Failed to open backup %d: %v", backups[i].Generation, err)
+		}
+		if len(entries) != expected {
+			t.Fatalf("Expected %d entries in backup %d, got %d", expected, backups[i].Generation, len(entries))
+		}
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	vault, _ := createTestVault(t)
+	vault.backups = 3
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	for _, username := range []string{"user1", "user2"} {
+		if err := vault.AddEntry(models.PasswordEntry{Username: username}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	if err := vault.Restore(1); err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to read restored vault: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Username != "user1" {
+		t.Fatalf("Expected the vault with only user1, got %+v", entries)
+	}
+
+	// The replaced vault becomes the newest backup, so the restore can be undone
+	if err := vault.Restore(1); err != nil {
+		t.Fatalf("Failed to undo restore: %v", err)
+	}
+	entries, _ = vault.GetAllEntries(masterPassword)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries after undoing the restore, got %d", len(entries))
+	}
+
+	if err := vault.Restore(9); err == nil {
+		t.Fatal("Restoring a missing backup should fail")
+	}
+}
+
+func TestChangeMasterPasswordReencryptsBackups(t *testing.T) {
+	vault, _ := createTestVault(t)
+	vault.backups = 3
+	oldPassword := "old-password"
+	newPassword := "new-password"
+	initTestVault(t, vault, oldPassword)
+	for _, username := range []string{"user1", "user2"} {
+		if err := vault.AddEntry(models.PasswordEntry{Username: username}, oldPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	// A backup left over from an earlier master password cannot be re-encrypted
+	stale := &VaultManager{vaultPath: vault.backupPath(3), kdf: testKDFParams}
+	if err := stale.Create("older-password"); err != nil {
+		t.Fatalf("Failed to create stale backup: %v", err)
+	}
+
+	if err := vault.ChangeMasterPassword(oldPassword, newPassword); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+
+	backups, err := vault.Backups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected the 2 backups from before the change and no stale one, got %+v", backups)
+	}
+
+	// The backups keep their contents and only open with the new password
+	for i, expected := range []int{1, 0} {
+		backup := &VaultManager{vaultPath: backups[i].Path, kdf: testKDFParams}
+		if _, err := backup.GetAllEntries(oldPassword); err == nil {
+			t.Fatalf("Backup %d still opens with the old password", backups[i].Generation)
+		}
+		entries, err := backup.GetAllEntries(newPassword)
+		if err != nil {
+			t.Fatalf("Failed to open backup %d with the new password: %v", backups[i].Generation, err)
+		}
+		if len(entries) != expected {
+			t.Fatalf("Expected %d entries in backup %d, got %d", expected, backups[i].Generation, len(entries))
+		}
+	}
+}
+
+func TestRestoreRejectsInvalidBackup(t *testing.T) {
+	vault, _ := createTestVault(t)
+	vault.backups = 1
+
+	if err := os.WriteFile(vault.backupPath(1), []byte("garbage"), 0600); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	if err := vault.Restore(1); err == nil {
+		t.Fatal("Restoring an invalid backup should fail")
+	}
+	if _, err := os.Stat(vault.vaultPath); !os.IsNotExist(err) {
+		t.Fatal("An invalid backup should not be written over the vault")
+	}
+}
+
+func TestRestoreRequiresBackups(t *testing.T) {
+	vault, _ := createTestVault(t)
+	initTestVault(t, vault, "master")
+
+	if err := os.WriteFile(vault.backupPath(1), []byte("garbage"), 0600); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	before, err := os.ReadFile(vault.vaultPath)
+	if err != nil {
+		t.Fatalf("Failed to read vault: %v", err)
+	}
+	if err := vault.Restore(1); !errors.Is(err, ErrBackupsDisabled) {
+		t.Fatalf("Expected ErrBackupsDisabled, got %v", err)
+	}
+	after, err := os.ReadFile(vault.vaultPath)
+	if err != nil {
+		t.Fatalf("Failed to read vault: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Fatal("A refused restore should leave the vault untouched")
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mpass/internal/crypto"
+)
+
+const (
+	// formatVersion is the current version of the vault file header.
+	formatVersion = 1
+
+	// legacySaltLength is the size of the raw salt that prefixed vaults written
+	// before the header was introduced.
+	legacySaltLength = 32
+
+	// legacyIterations is the PBKDF2 iteration count used by headerless vaults.
+	legacyIterations = 100000
+)
+
+// vaultMagic identifies a vault file that starts with a vaultHeader.
+var vaultMagic = []byte("MPVT")
+
+// vaultHeader is the self-describing header stored in front of the encrypted vault data.
+//
+// On disk it is laid out as:
+//
+//	magic (4) | version (1) | kdf id (1) | kdf params length (2) | kdf params |
+//	cipher id (1) | salt length (1) | salt
+//
+// All integers are big-endian.
+type vaultHeader struct {
+	Version uint8
+	KDF     crypto.KDFParams
+	Cipher  crypto.Cipher
+	Salt    []byte
+
+	// legacy is set when the header was synthesized for a headerless vault file.
+	legacy bool
+}
+
+// newVaultHeader returns a header for a new vault using the given key derivation
+// parameters and a freshly generated salt.
+func newVaultHeader(params crypto.KDFParams) (*vaultHeader, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultHeader{
+		Version: formatVersion,
+		KDF:     params,
+		Cipher:  crypto.CipherAES256GCM,
+		Salt:    salt,
+	}, nil
+}
+
+// MarshalBinary encodes the header in the current on-disk format.
+func (h *vaultHeader) MarshalBinary() ([]byte, error) {
+	params, err := h.KDF.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0xFFFF {
+		return nil, fmt.Errorf("kdf parameters too long")
+	}
+	if len(h.Salt) == 0 || len(h.Salt) > 0xFF {
+		return nil, fmt.Errorf("invalid salt length: %d", len(h.Salt))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(vaultMagic)
+	buf.WriteByte(formatVersion)
+	buf.WriteByte(byte(h.KDF.Algorithm))
+	buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(params))))
+	buf.Write(params)
+	buf.WriteByte(byte(h.Cipher))
+	buf.WriteByte(byte(len(h.Salt)))
+	buf.Write(h.Salt)
+
+	return buf.Bytes(), nil
+}
+
+// parseVaultFile splits the contents of a vault file into its header and ciphertext.
+// Files without the header magic are treated as the legacy layout: a 32-byte salt
+// followed by the ciphertext, protected with PBKDF2-SHA256 at 100,000 iterations.
+func parseVaultFile(data []byte) (*vaultHeader, []byte, error) {
+	if !bytes.HasPrefix(data, vaultMagic) {
+		return parseLegacyVaultFile(data)
+	}
+
+	r := bytes.NewReader(data[len(vaultMagic):])
+
+	var fixed struct {
+		Version   uint8
+		KDF       uint8
+		ParamsLen uint16
+	}
+	if err := binary.Read(r, binary.BigEndian, &fixed); err != nil {
+		return nil, nil, fmt.Errorf("invalid vault header: %w", err)
+	}
+	if fixed.Version != formatVersion {
+		return nil, nil, fmt.Errorf("unsupported vault format version: %d", fixed.Version)
+	}
+
+	rawParams := make([]byte, fixed.ParamsLen)
+	if _, err := io.ReadFull(r, rawParams); err != nil || r.Len() < 2 {
+		return nil, nil, fmt.Errorf("invalid vault header: truncated kdf parameters")
+	}
+	params, err := crypto.UnmarshalKDFParams(crypto.KDF(fixed.KDF), rawParams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid vault header: %w", err)
+	}
+
+	cipherID, _ := r.ReadByte()
+	if crypto.Cipher(cipherID) != crypto.CipherAES256GCM {
+		return nil, nil, fmt.Errorf("unsupported vault cipher: %s", crypto.Cipher(cipherID))
+	}
+
+	saltLen, _ := r.ReadByte()
+	if saltLen == 0 || r.Len() < int(saltLen) {
+		return nil, nil, fmt.Errorf("invalid vault header: truncated salt")
+	}
+	salt := make([]byte, saltLen)
+	_, _ = io.ReadFull(r, salt)
+
+	header := &vaultHeader{
+		Version: fixed.Version,
+		KDF:     params,
+		Cipher:  crypto.Cipher(cipherID),
+		Salt:    salt,
+	}
+	return header, data[len(data)-r.Len():], nil
+}
+
+// parseLegacyVaultFile handles vault files written before the header was introduced.
+func parseLegacyVaultFile(data []byte) (*vaultHeader, []byte, error) {
+	if len(data) < legacySaltLength {
+		return nil, nil, fmt.Errorf("invalid vault file format")
+	}
+
+	salt := make([]byte, legacySaltLength)
+	copy(salt, data[:legacySaltLength])
+
+	header := &vaultHeader{
+		Version: 0,
+		KDF:     crypto.KDFParams{Algorithm: crypto.KDFPBKDF2SHA256, Iterations: legacyIterations},
+		Cipher:  crypto.CipherAES256GCM,
+		Salt:    salt,
+		legacy:  true,
+	}
+	return header, data[legacySaltLength:], nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"mpass/internal/crypto"
+	"mpass/internal/models"
+	"os"
+	"testing"
+)
+
+func TestVaultHeaderRoundTrip(t *testing.T) {
+	header, err := newVaultHeader(crypto.DefaultKDFParams())
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	data, err := header.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal header: %v", err)
+	}
+
+	ciphertext := []byte("ciphertext")
+	parsed, rest, err := parseVaultFile(append(data, ciphertext...))
+	if err != nil {
+		t.Fatalf("Failed to parse header: %v", err)
+	}
+
+	if parsed.Version != formatVersion {
+		t.Fatalf("Expected version %d, got %d", formatVersion, parsed.Version)
+	}
+	if parsed.KDF != header.KDF {
+		t.Fatalf("Expected KDF params %+v, got %+v", header.KDF, parsed.KDF)
+	}
+	if parsed.Cipher != crypto.CipherAES256GCM {
+		t.Fatalf("Expected cipher %s, got %s", crypto.CipherAES256GCM, parsed.Cipher)
+	}
+	if !bytes.Equal(parsed.Salt, header.Salt) {
+		t.Fatal("Salt was not preserved")
+	}
+	if !bytes.Equal(rest, ciphertext) {
+		t.Fatalf("Expected ciphertext %q, got %q", ciphertext, rest)
+	}
+}
+
+func TestParseVaultFileRejectsBadHeaders(t *testing.T) {
+	header, _ := newVaultHeader(crypto.DefaultKDFParams())
+	data, _ := header.MarshalBinary()
+
+	badVersion := append([]byte{}, data...)
+	badVersion[len(vaultMagic)] = 99
+	if _, _, err := parseVaultFile(badVersion); err == nil {
+		t.Fatal("Unknown format version should be rejected")
+	}
+
+	if _, _, err := parseVaultFile(data[:len(vaultMagic)+3]); err == nil {
+		t.Fatal("Truncated header should be rejected")
+	}
+
+	if _, _, err := parseVaultFile([]byte("short")); err == nil {
+		t.Fatal("Short legacy file should be rejected")
+	}
+}
+
+func TestLegacyVaultIsUpgradedOnSave(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+
+	// Write a vault in the original headerless layout: salt followed by ciphertext
+	salt, _ := crypto.GenerateSalt()
+	plaintext, _ := json.Marshal(models.Vault{
+		Entries: []models.PasswordEntry{{Username: "legacy", URL: "https://old.example", Password: "pass"}},
+		Salt:    salt,
+	})
+	ciphertext, err := crypto.Encrypt(plaintext, crypto.DeriveKey(masterPassword, salt))
+	if err != nil {
+		t.Fatalf("Failed to encrypt legacy vault: %v", err)
+	}
+	if err := os.WriteFile(vault.vaultPath, append(salt, ciphertext...), 0600); err != nil {
+		t.Fatalf("Failed to write legacy vault: %v", err)
+	}
+
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to read legacy vault: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Username != "legacy" {
+		t.Fatalf("Unexpected entries in legacy vault: %+v", entries)
+	}
+
+	if err := vault.AddEntry(models.PasswordEntry{Username: "new"}, masterPassword); err != nil {
+		t.Fatalf("Failed to add entry to legacy vault: %v", err)
+	}
+
+	data, _ := os.ReadFile(vault.vaultPath)
+	header, _, err := parseVaultFile(data)
+	if err != nil {
+		t.Fatalf("Failed to parse upgraded vault: %v", err)
+	}
+	if header.legacy || header.Version != formatVersion {
+		t.Fatal("Vault should have been rewritten with a header")
+	}
+	if !bytes.Equal(header.Salt, salt) {
+		t.Fatal("Upgrading the layout should keep the existing salt")
+	}
+
+	entries, err = vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to read upgraded vault: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries after upgrade, got %d", len(entries))
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultLockTimeout is how long a vault transaction waits for another process to finish.
+	defaultLockTimeout = 10 * time.Second
+
+	// lockPollInterval is how often a busy lock is retried while waiting.
+	lockPollInterval = 50 * time.Millisecond
+)
+
+// LockedError is returned when the vault lock could not be acquired before the timeout.
+type LockedError struct {
+	PID int // process holding the lock, or 0 if unknown
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return "vault is locked by another process"
+	}
+	return fmt.Sprintf("vault is locked by PID %d", e.PID)
+}
+
+// lockPath returns the path of the lock file that guards the vault.
+func (v *VaultManager) lockPath() string {
+	return v.vaultPath + ".lock"
+}
+
+// withLock runs fn while holding an exclusive advisory lock on the vault's lock file,
+// so that concurrent mpass processes cannot interleave their read-modify-write cycles.
+// It waits up to the manager's lock timeout and returns a *LockedError naming the PID
+// of the holder if the lock cannot be acquired in time.
+func (v *VaultManager) withLock(fn func() error) error {
+	if err := v.ensureVaultDir(); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+
+	f, err := os.OpenFile(v.lockPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open vault lock file: %w", err)
+	}
+	defer f.Close()
+
+	deadline := time.Now().Add(v.lockTimeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return &LockedError{PID: readLockPID(f)}
+		}
+		time.Sleep(lockPollInterval)
+	}
+	defer unlockFile(f)
+
+	// Record the holder so that waiting processes can report who has the vault
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+		defer f.Truncate(0)
+	}
+
+	return fn()
+}
+
+// readLockPID returns the PID recorded in the lock file, or 0 if it cannot be read.
+func readLockPID(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
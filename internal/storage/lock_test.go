@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"mpass/internal/models"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithLockReleasesLock(t *testing.T) {
+	vault, _ := createTestVault(t)
+
+	for i := 0; i < 2; i++ {
+		if err := vault.withLock(func() error { return nil }); err != nil {
+			t.Fatalf("Failed to take the lock (attempt %d): %v", i+1, err)
+		}
+	}
+
+	data, _ := os.ReadFile(vault.lockPath())
+	if len(data) != 0 {
+		t.Fatalf("Lock file should be cleared after release, got %q", data)
+	}
+}
+
+func TestWithLockTimesOut(t *testing.T) {
+	vault, _ := createTestVault(t)
+	initTestVault(t, vault, "test-password")
+	vault.lockTimeout = 100 * time.Millisecond
+
+	// Hold the lock through a separate file handle, as another process would
+	if err := vault.ensureVaultDir(); err != nil {
+		t.Fatalf("Failed to create vault dir: %v", err)
+	}
+	holder, err := os.OpenFile(vault.lockPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open lock file: %v", err)
+	}
+	defer holder.Close()
+	if locked, err := tryLockFile(holder); err != nil || !locked {
+		t.Fatalf("Failed to take the lock (locked=%v, err=%v)", locked, err)
+	}
+	if _, err := holder.WriteAt([]byte("4242"), 0); err != nil {
+		t.Fatalf("Failed to write PID: %v", err)
+	}
+
+	err = vault.AddEntry(models.PasswordEntry{Username: "testuser"}, "test-password")
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected a LockedError, got %v", err)
+	}
+	if lockedErr.PID != 4242 {
+		t.Fatalf("Expected PID 4242, got %d", lockedErr.PID)
+	}
+	if err.Error() != "vault is locked by PID 4242" {
+		t.Fatalf("Unexpected error message: %v", err)
+	}
+
+	if err := unlockFile(holder); err != nil {
+		t.Fatalf("Failed to release the lock: %v", err)
+	}
+	if err := vault.AddEntry(models.PasswordEntry{Username: "testuser"}, "test-password"); err != nil {
+		t.Fatalf("Add should succeed once the lock is released: %v", err)
+	}
+}
+
+func TestConcurrentUpdatesAreSerialized(t *testing.T) {
+	vault, _ := createTestVault(t)
+	vault.lockTimeout = 10 * time.Second
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	const writers = 5
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func() {
+			// Each writer uses its own manager and file handle, like separate processes
+			other := &VaultManager{vaultPath: vault.vaultPath, kdf: testKDFParams, lockTimeout: vault.lockTimeout}
+			errs <- other.AddEntry(models.PasswordEntry{Username: "user"}, masterPassword)
+		}()
+	}
+	for i := 0; i < writers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Concurrent add failed: %v", err)
+		}
+	}
+
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to get entries: %v", err)
+	}
+	if len(entries) != writers {
+		t.Fatalf("Expected %d entries, got %d (lost updates)", writers, len(entries))
+	}
+}
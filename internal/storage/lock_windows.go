@@ -0,0 +1,32 @@
+//go:build windows
+
+package storage
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRegion is the byte range locked on Windows. It lies far beyond the PID written
+// at the start of the file, because locked bytes cannot be read by other processes.
+var lockRegion = windows.Overlapped{OffsetHigh: 1}
+
+// tryLockFile attempts to take an exclusive lock on f without blocking.
+// It reports false if another process already holds the lock.
+func tryLockFile(f *os.File) (bool, error) {
+	ol := lockRegion
+	err := windows.LockFileEx(windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlockFile releases a lock taken with tryLockFile.
+func unlockFile(f *os.File) error {
+	ol := lockRegion
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &ol)
+}
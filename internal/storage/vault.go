@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mpass/internal/crypto"
 	"mpass/internal/models"
@@ -11,21 +13,58 @@ import (
 	"time"
 )
 
-const (
-	vaultDir  = ".mpass"
-	vaultFile = "vault.enc"
-)
-
 type VaultManager struct {
-	vaultPath string
+	vaultPath   string
+	kdf         crypto.KDFParams
+	backups     int
+	lockTimeout time.Duration
+
+	// key, when set by Unlock or UseKey, is used instead of deriving a key from the master password.
+	key []byte
+}
+
+// NewVault creates a new VaultManager instance for the vault file at vaultPath.
+func NewVault(vaultPath string) *VaultManager {
+	return &VaultManager{
+		vaultPath:   vaultPath,
+		kdf:         crypto.DefaultKDFParams(),
+		backups:     DefaultBackups,
+		lockTimeout: defaultLockTimeout,
+	}
 }
 
-// NewVault creates a new VaultManager instance with the default vault path
-// located in the user's home directory.
-func NewVault() *VaultManager {
-	homeDir, _ := os.UserHomeDir()
-	vaultPath := filepath.Join(homeDir, vaultDir, vaultFile)
-	return &VaultManager{vaultPath: vaultPath}
+// Path returns the location of the vault file.
+func (v *VaultManager) Path() string {
+	return v.vaultPath
+}
+
+// SetKDFParams sets the key derivation parameters used when a vault is created
+// or re-encrypted. Existing vaults keep the parameters stored in their header.
+func (v *VaultManager) SetKDFParams(params crypto.KDFParams) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	v.kdf = params
+	return nil
+}
+
+// SetBackups sets how many previous generations of the vault are kept as vault.enc.1..n.
+// Zero disables backups.
+func (v *VaultManager) SetBackups(n int) error {
+	if n < 0 {
+		return fmt.Errorf("number of backups cannot be negative")
+	}
+	v.backups = n
+	return nil
+}
+
+// SetLockTimeout sets how long vault changes wait for another mpass process to release the vault.
+func (v *VaultManager) SetLockTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("lock timeout cannot be negative")
+	}
+	v.lockTimeout = timeout
+	return nil
 }
 
 // ensureVaultDir creates the directory for the vault file if it does not exist.
@@ -35,20 +74,72 @@ func (v *VaultManager) ensureVaultDir() error {
 	return os.MkdirAll(dir, 0700)
 }
 
-// loadVault loads the encrypted vault from disk, decrypts it using the provided master password,
-// and returns the Vault model. If the vault file does not exist, it creates a new vault with a random salt.
-// Returns an error if reading, decrypting, or parsing the vault fails.
-func (v *VaultManager) loadVault(masterPassword string) (*models.Vault, error) {
-	if _, err := os.Stat(v.vaultPath); os.IsNotExist(err) {
-		// Create new vault with random salt
-		salt, err := crypto.GenerateSalt()
+var (
+	// ErrVaultNotFound is returned when the vault file does not exist yet.
+	ErrVaultNotFound = errors.New("no vault found, run mpass init")
+	// ErrVaultExists is returned by Create when there already is a vault file.
+	ErrVaultExists = errors.New("a vault already exists")
+)
+
+// Exists reports whether the vault file has been created.
+func (v *VaultManager) Exists() bool {
+	_, err := os.Stat(v.vaultPath)
+	return err == nil
+}
+
+// Create initializes a new, empty vault encrypted with masterPassword, using a random salt
+// and the manager's key derivation parameters. Returns ErrVaultExists if the vault file
+// already exists, so an existing vault can never be replaced by accident.
+func (v *VaultManager) Create(masterPassword string) error {
+	if masterPassword == "" {
+		return fmt.Errorf("master password cannot be empty")
+	}
+
+	return v.withLock(func() error {
+		if v.Exists() {
+			return fmt.Errorf("%w at %s", ErrVaultExists, v.vaultPath)
+		}
+
+		header, err := newVaultHeader(v.kdf)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate salt: %w", err)
+			return fmt.Errorf("failed to generate salt: %w", err)
 		}
-		return &models.Vault{
-			Entries: []models.PasswordEntry{},
-			Salt:    salt,
-		}, nil
+		key, err := crypto.DeriveKeyWithParams(masterPassword, header.Salt, header.KDF)
+		if err != nil {
+			return fmt.Errorf("failed to derive key: %w", err)
+		}
+
+		return v.saveVault(&unlockedVault{
+			Vault: &models.Vault{
+				Entries: []models.PasswordEntry{},
+				Salt:    header.Salt,
+			},
+			header: header,
+			key:    key,
+		})
+	})
+}
+
+// unlockedVault is a decrypted vault together with the header and key it was opened with,
+// so that it can be written back without deriving the key again.
+type unlockedVault struct {
+	*models.Vault
+	header *vaultHeader
+	key    []byte
+
+	// dirty is set when loading changed the vault (for example by assigning missing
+	// entry IDs) and the change should be written back even by read-only operations.
+	dirty bool
+}
+
+// loadVault loads the encrypted vault from disk, decrypts it using the provided master password,
+// and returns it along with its header and derived key. Headerless vaults written by older versions
+// are read as well and are rewritten with a header on the next save. Entries without an ID get a new one.
+// Returns ErrVaultNotFound if the vault has not been created with Create, or an error if reading,
+// decrypting, or parsing the vault fails.
+func (v *VaultManager) loadVault(masterPassword string) (*unlockedVault, error) {
+	if !v.Exists() {
+		return nil, ErrVaultNotFound
 	}
 
 	// Load existing vault
@@ -57,16 +148,19 @@ func (v *VaultManager) loadVault(masterPassword string) (*models.Vault, error) {
 		return nil, fmt.Errorf("failed to read vault file: %w", err)
 	}
 
-	// First, we need to extract the salt from the beginning of the file
-	if len(encryptedData) < 32 {
-		return nil, fmt.Errorf("invalid vault file format")
+	header, ciphertext, err := parseVaultFile(encryptedData)
+	if err != nil {
+		return nil, err
 	}
 
-	salt := encryptedData[:32]
-	ciphertext := encryptedData[32:]
-
-	// Derive key and decrypt
-	key := crypto.DeriveKey(masterPassword, salt)
+	// Derive key (unless the vault was unlocked with one) and decrypt
+	key := v.key
+	if key == nil {
+		key, err = crypto.DeriveKeyWithParams(masterPassword, header.Salt, header.KDF)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+	}
 	decryptedData, err := crypto.Decrypt(ciphertext, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt vault (wrong password?): %w", err)
@@ -77,96 +171,412 @@ func (v *VaultManager) loadVault(masterPassword string) (*models.Vault, error) {
 		return nil, fmt.Errorf("failed to parse vault data: %w", err)
 	}
 
-	vault.Salt = salt
-	return &vault, nil
+	vault.Salt = header.Salt
+	unlocked := &unlockedVault{Vault: &vault, header: header, key: key}
+
+	// Backfill IDs for entries written before entries had one
+	for i := range vault.Entries {
+		if vault.Entries[i].ID != "" {
+			continue
+		}
+		id, err := models.NewID()
+		if err != nil {
+			return nil, err
+		}
+		vault.Entries[i].ID = id
+		unlocked.dirty = true
+	}
+
+	return unlocked, nil
+}
+
+// Unlock derives the vault key from masterPassword, checks that it opens the vault and keeps
+// it, so that later operations skip the key derivation and ignore their masterPassword argument.
+// The key is returned so that it can be handed to the agent.
+func (v *VaultManager) Unlock(masterPassword string) ([]byte, error) {
+	v.key = nil
+	vault, err := v.loadVault(masterPassword)
+	if err != nil {
+		return nil, err
+	}
+	v.key = vault.key
+	return vault.key, nil
+}
+
+// Key returns the key the vault is unlocked with, or nil if it is not unlocked.
+func (v *VaultManager) Key() []byte {
+	return v.key
+}
+
+// UseKey unlocks the vault with a key previously returned by Unlock instead of a master password.
+// Returns an error, and leaves the manager locked, if the key does not open the vault.
+func (v *VaultManager) UseKey(key []byte) error {
+	v.key = key
+	if _, err := v.loadVault(""); err != nil {
+		v.key = nil
+		return err
+	}
+	return nil
 }
 
-// saveVault serializes the given Vault struct, encrypts it using the provided master password,
-// and writes the encrypted data (with prepended salt) to disk. It ensures the vault directory exists
-// and returns an error if any step fails.
-func (v *VaultManager) saveVault(vault *models.Vault, masterPassword string) error {
+// view loads the vault for a read-only operation. If loading had to change the vault,
+// such as assigning IDs to old entries, the change is saved first so that it is stable
+// across invocations.
+func (v *VaultManager) view(masterPassword string) (*unlockedVault, error) {
+	vault, err := v.loadVault(masterPassword)
+	if err != nil || !vault.dirty {
+		return vault, err
+	}
+
+	err = v.update(masterPassword, func(locked *unlockedVault) error {
+		vault = locked
+		return nil
+	})
+	return vault, err
+}
+
+// saveVault serializes the given vault, encrypts it with the key it was unlocked with,
+// and atomically replaces the vault file with the header followed by the encrypted data,
+// keeping the previous file as a backup. Legacy vaults are upgraded to the current header
+// format. It ensures the vault directory exists and returns an error if any step fails.
+func (v *VaultManager) saveVault(vault *unlockedVault) error {
 	if err := v.ensureVaultDir(); err != nil {
 		return fmt.Errorf("failed to create vault directory: %w", err)
 	}
 
+	finalData, err := encodeVault(vault)
+	if err != nil {
+		return err
+	}
+
+	if err := v.writeVaultFile(finalData, nil); err != nil {
+		return err
+	}
+	if v.key != nil {
+		v.key = vault.key // keep the unlocked key in step with a re-encrypted vault
+	}
+	return nil
+}
+
+// update runs a read-modify-write transaction on the vault: it takes the vault lock, loads
+// and decrypts the vault, applies fn and saves the result before releasing the lock.
+// If fn returns an error nothing is written.
+func (v *VaultManager) update(masterPassword string, fn func(vault *unlockedVault) error) error {
+	return v.withLock(func() error {
+		vault, err := v.loadVault(masterPassword)
+		if err != nil {
+			return err
+		}
+		if err := fn(vault); err != nil {
+			return err
+		}
+		return v.saveVault(vault)
+	})
+}
+
+// encodeVault serializes and encrypts an unlocked vault, returning the complete file
+// contents: the current header followed by the encrypted data.
+func encodeVault(vault *unlockedVault) ([]byte, error) {
 	// Serialize vault data
-	data, err := json.Marshal(vault)
+	data, err := json.Marshal(vault.Vault)
 	if err != nil {
-		return fmt.Errorf("failed to serialize vault: %w", err)
+		return nil, fmt.Errorf("failed to serialize vault: %w", err)
 	}
+	return sealVault(data, vault.header, vault.key)
+}
 
-	// Derive key and encrypt
-	key := crypto.DeriveKey(masterPassword, vault.Salt)
+// sealVault encrypts serialized vault data with key and returns the complete file contents:
+// header, in the current format, followed by the encrypted data.
+func sealVault(data []byte, vaultHeader *vaultHeader, key []byte) ([]byte, error) {
 	encryptedData, err := crypto.Encrypt(data, key)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt vault: %w", err)
+		return nil, fmt.Errorf("failed to encrypt vault: %w", err)
+	}
+
+	header := *vaultHeader
+	header.Version = formatVersion
+	header.legacy = false
+	headerData, err := header.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vault header: %w", err)
 	}
 
-	// Prepend salt to encrypted data
-	finalData := append(vault.Salt, encryptedData...)
+	// Prepend header to encrypted data
+	return append(headerData, encryptedData...), nil
+}
 
-	// Write to file with secure permissions
-	if err := os.WriteFile(v.vaultPath, finalData, 0600); err != nil {
-		return fmt.Errorf("failed to write vault file: %w", err)
+// readHeader reads and parses only the header of the vault file, without decrypting it.
+func (v *VaultManager) readHeader() (*vaultHeader, error) {
+	data, err := os.ReadFile(v.vaultPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault file: %w", err)
 	}
 
-	return nil
+	header, _, err := parseVaultFile(data)
+	return header, err
 }
 
-// AddEntry adds a new password entry to the vault, setting the creation and update timestamps,
-// and saves the updated vault encrypted with the provided master password.
-// Returns an error if loading or saving the vault fails.
-func (v *VaultManager) AddEntry(entry models.PasswordEntry, masterPassword string) error {
-	vault, err := v.loadVault(masterPassword)
+// rekey replaces the salt and key derivation parameters of an unlocked vault and derives
+// the new key from masterPassword. The change only takes effect on disk once the vault is saved.
+func (v *VaultManager) rekey(vault *unlockedVault, masterPassword string, params crypto.KDFParams) error {
+	header, err := newVaultHeader(params)
 	if err != nil {
 		return err
 	}
 
-	entry.CreatedAt = time.Now()
-	entry.UpdatedAt = time.Now()
-	vault.Entries = append(vault.Entries, entry)
+	key, err := crypto.DeriveKeyWithParams(masterPassword, header.Salt, header.KDF)
+	if err != nil {
+		return fmt.Errorf("failed to derive key: %w", err)
+	}
 
-	return v.saveVault(vault, masterPassword)
+	vault.header = header
+	vault.key = key
+	vault.Salt = header.Salt
+	return nil
 }
 
-// UpdateEntries updates an existing password entry in the vault, preserving the creation timestamp
-// and updating the modification timestamp. It saves the updated vault encrypted with the provided master password.
-func (v *VaultManager) UpdateEntries(entries []models.PasswordEntry, masterPassword string) error {
+// NeedsKDFUpgrade reports whether the vault on disk is still protected with PBKDF2
+// and should be re-encrypted with Argon2id. Only the header is read, so no master
+// password is needed. A vault that does not exist yet needs no upgrade.
+func (v *VaultManager) NeedsKDFUpgrade() (bool, error) {
+	if !v.Exists() {
+		return false, nil
+	}
+	header, err := v.readHeader()
+	if err != nil {
+		return false, err
+	}
+	return header.KDF.Algorithm == crypto.KDFPBKDF2SHA256, nil
+}
+
+// UpgradeKDF unlocks a PBKDF2 vault with the master password and re-encrypts it using
+// Argon2id with the manager's key derivation parameters and a fresh salt. The backups are
+// re-encrypted the same way, so that no PBKDF2 copy of the vault is left on disk.
+// confirm is called once the master password has been verified, before the vault is locked,
+// so that waiting for the user does not hold up other processes; if it returns false the
+// vault is left untouched. Reports whether the vault was re-encrypted, which it is not if
+// another process upgraded it in the meantime.
+func (v *VaultManager) UpgradeKDF(masterPassword string, confirm func() (bool, error)) (bool, error) {
 	vault, err := v.loadVault(masterPassword)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if vault.header.KDF.Algorithm != crypto.KDFPBKDF2SHA256 {
+		return false, nil
 	}
+	if ok, err := confirm(); err != nil || !ok {
+		return false, err
+	}
+
+	upgraded := false
+	err = v.withLock(func() error {
+		// Reuse the key derived above unless the vault was re-encrypted since
+		header, err := v.readHeader()
+		if err != nil {
+			return err
+		}
+		if header.KDF.Algorithm != crypto.KDFPBKDF2SHA256 {
+			return nil
+		}
+		current := &VaultManager{vaultPath: v.vaultPath}
+		if bytes.Equal(header.Salt, vault.header.Salt) && header.KDF == vault.header.KDF {
+			current.key = vault.key
+		}
+		locked, err := current.loadVault(masterPassword)
+		if err != nil {
+			return err
+		}
 
-	vault.Entries = entries
+		params := v.kdf
+		if params.Algorithm != crypto.KDFArgon2id {
+			params = crypto.DefaultKDFParams()
+		}
+		previous := *locked
+		if err := v.rekey(locked, masterPassword, params); err != nil {
+			return err
+		}
 
-	return v.saveVault(vault, masterPassword)
+		data, err := encodeVault(locked)
+		if err != nil {
+			return err
+		}
+		if err := v.writeRekeyedVault(data, locked, &previous, masterPassword, nil); err != nil {
+			return err
+		}
+		upgraded = true
+		return nil
+	})
+	return upgraded, err
 }
 
-// DeleteEntry removes a password entry from the vault by matching the username and URL.
-// It loads the vault using the provided master password, filters out the entry to be deleted,
-// and saves the updated vault. Returns an error if loading or saving the vault fails.
-func (v *VaultManager) DeleteEntry(entry *models.PasswordEntry, masterPassword string) error {
-	vault, err := v.loadVault(masterPassword)
-	if err != nil {
-		return err
+// ChangeMasterPassword unlocks the vault with oldPassword and re-encrypts it with newPassword,
+// using a fresh salt and the manager's key derivation parameters. The new vault is written to
+// a temporary file and verified before it replaces the old one, so the old vault stays readable
+// if anything fails along the way. The backups are then re-encrypted with newPassword too, so
+// that the old master password no longer opens any copy of the vault. A manager that was
+// unlocked stays unlocked with the new key.
+func (v *VaultManager) ChangeMasterPassword(oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return fmt.Errorf("new master password cannot be empty")
 	}
 
-	var updatedEntries []models.PasswordEntry
-	for _, e := range vault.Entries {
-		if e.Username != entry.Username || e.URL != entry.URL {
-			updatedEntries = append(updatedEntries, e)
+	return v.withLock(func() error {
+		vault, err := v.loadVault(oldPassword)
+		if err != nil {
+			return err
 		}
+
+		previous := *vault
+		if err := v.rekey(vault, newPassword, v.kdf); err != nil {
+			return err
+		}
+
+		data, err := encodeVault(vault)
+		if err != nil {
+			return err
+		}
+
+		return v.writeRekeyedVault(data, vault, &previous, oldPassword, func(tmpPath string) error {
+			written := &VaultManager{vaultPath: tmpPath, kdf: v.kdf}
+			if _, err := written.loadVault(newPassword); err != nil {
+				return fmt.Errorf("failed to verify re-encrypted vault: %w", err)
+			}
+			return nil
+		})
+	})
+}
+
+var (
+	// ErrEntryNotFound is returned when no entry matches the requested ID.
+	ErrEntryNotFound = errors.New("entry not found")
+	// ErrEntryChanged is returned when an entry being updated was saved by someone else
+	// since it was read.
+	ErrEntryChanged = errors.New("entry was changed by another process since it was read, please try again")
+)
+
+// findEntry returns the index of the entry with the given ID. An unambiguous prefix of an
+// ID, such as the short ID shown by "list", is accepted as well.
+// Returns ErrEntryNotFound if nothing matches, or an error if the prefix is ambiguous.
+func findEntry(entries []models.PasswordEntry, id string) (int, error) {
+	if id == "" {
+		return -1, ErrEntryNotFound
+	}
+
+	match := -1
+	for i, entry := range entries {
+		if entry.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(entry.ID, id) {
+			if match >= 0 {
+				return -1, fmt.Errorf("entry ID %q is ambiguous", id)
+			}
+			match = i
+		}
+	}
+
+	if match < 0 {
+		return -1, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	return match, nil
+}
+
+// AddEntry adds a new password entry to the vault, assigning it a new ID and setting the
+// creation and update timestamps, and saves the updated vault encrypted with the provided
+// master password. Returns an error if loading or saving the vault fails.
+func (v *VaultManager) AddEntry(entry models.PasswordEntry, masterPassword string) error {
+	return v.update(masterPassword, func(vault *unlockedVault) error {
+		id, err := models.NewID()
+		if err != nil {
+			return err
+		}
+
+		entry.ID = id
+		entry.CreatedAt = time.Now()
+		entry.UpdatedAt = time.Now()
+		vault.Entries = append(vault.Entries, entry)
+		return nil
+	})
+}
+
+// GetEntry loads the vault using the provided master password and returns the entry with the given ID
+// (or unambiguous ID prefix). Returns ErrEntryNotFound if there is no such entry.
+func (v *VaultManager) GetEntry(id, masterPassword string) (*models.PasswordEntry, error) {
+	vault, err := v.view(masterPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findEntry(vault.Entries, id)
+	if err != nil {
+		return nil, err
 	}
+	return &vault.Entries[i], nil
+}
+
+// UpdateEntry replaces the stored entry that has the same ID as entry, preserving its creation
+// timestamp and password history and updating its modification timestamp. A changed password
+// moves the stored one into the history. It saves the updated vault encrypted with the provided
+// master password. entry must be a modified copy of the stored entry: ErrEntryChanged is
+// returned if the stored entry has been updated since entry was read, so that a concurrent
+// change is not silently reverted. Returns ErrEntryNotFound if there is no entry with that ID.
+func (v *VaultManager) UpdateEntry(entry models.PasswordEntry, masterPassword string) error {
+	return v.UpdateEntries([]models.PasswordEntry{entry}, masterPassword)
+}
+
+// UpdateEntries replaces several stored entries the way UpdateEntry does, in a single save:
+// either all of them are updated or, if one is not found or has changed, none is.
+func (v *VaultManager) UpdateEntries(entries []models.PasswordEntry, masterPassword string) error {
+	return v.update(masterPassword, func(vault *unlockedVault) error {
+		now := time.Now()
+		for _, entry := range entries {
+			i, err := findEntry(vault.Entries, entry.ID)
+			if err != nil {
+				return err
+			}
+			if vault.Entries[i].ID != entry.ID {
+				return fmt.Errorf("%w: %s", ErrEntryNotFound, entry.ID)
+			}
+
+			stored := vault.Entries[i]
+			if !entry.UpdatedAt.Equal(stored.UpdatedAt) {
+				return fmt.Errorf("%w: %s", ErrEntryChanged, entry.Title())
+			}
+			entry.CreatedAt = stored.CreatedAt
+			entry.UpdatedAt = now
+			entry.History = stored.History
+			if entry.Password != stored.Password && stored.Password != "" {
+				entry.RecordPrevious(stored.Password, now)
+			}
+			vault.Entries[i] = entry
+		}
+		return nil
+	})
+}
 
-	vault.Entries = updatedEntries
+// DeleteEntry removes the password entry with the given ID from the vault.
+// It loads the vault using the provided master password, removes the entry, and saves the
+// updated vault. Returns ErrEntryNotFound if there is no entry with that ID, or an error if
+// loading or saving the vault fails.
+func (v *VaultManager) DeleteEntry(id, masterPassword string) error {
+	return v.update(masterPassword, func(vault *unlockedVault) error {
+		i, err := findEntry(vault.Entries, id)
+		if err != nil {
+			return err
+		}
+		if vault.Entries[i].ID != id {
+			return fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+		}
 
-	return v.saveVault(vault, masterPassword)
+		vault.Entries = append(vault.Entries[:i], vault.Entries[i+1:]...)
+		return nil
+	})
 }
 
 // GetAllEntries loads the vault using the provided master password and returns all password entries.
 // Returns a slice of PasswordEntry and an error if loading the vault fails.
 func (v *VaultManager) GetAllEntries(masterPassword string) ([]models.PasswordEntry, error) {
-	vault, err := v.loadVault(masterPassword)
+	vault, err := v.view(masterPassword)
 	if err != nil {
 		return nil, err
 	}
@@ -178,7 +588,7 @@ func (v *VaultManager) GetAllEntries(masterPassword string) ([]models.PasswordEn
 // on the username and URL fields. If either parameter is an empty string, it is ignored in the search.
 // Returns a slice of matching PasswordEntry structs and an error if loading the vault fails.
 func (v *VaultManager) SearchEntries(username, url, masterPassword string) ([]models.PasswordEntry, error) {
-	vault, err := v.loadVault(masterPassword)
+	vault, err := v.view(masterPassword)
 	if err != nil {
 		return nil, err
 	}
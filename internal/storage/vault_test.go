@@ -1,34 +1,51 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
+	"mpass/internal/crypto"
 	"mpass/internal/models"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
+// testKDFParams keeps Argon2id cheap so the tests stay fast.
+var testKDFParams = crypto.KDFParams{Algorithm: crypto.KDFArgon2id, Time: 1, Memory: 64, Parallelism: 1}
+
 func createTestVault(t *testing.T) (*VaultManager, string) {
 	tempDir := t.TempDir()
 	vaultPath := filepath.Join(tempDir, "test_vault.enc")
-	return &VaultManager{vaultPath: vaultPath}, tempDir
+	return &VaultManager{vaultPath: vaultPath, kdf: testKDFParams}, tempDir
+}
+
+// initTestVault creates the vault file so that entries can be added to it.
+func initTestVault(t *testing.T, vault *VaultManager, masterPassword string) {
+	if err := vault.Create(masterPassword); err != nil {
+		t.Fatalf("Failed to create vault: %v", err)
+	}
 }
 
 func TestNewVault(t *testing.T) {
-	vault := NewVault()
+	expectedPath := filepath.Join(t.TempDir(), "work.enc")
+	vault := NewVault(expectedPath)
 	if vault == nil {
 		t.Fatal("NewVault() returned nil")
 	}
 
-	homeDir, _ := os.UserHomeDir()
-	expectedPath := filepath.Join(homeDir, ".mpass", "vault.enc")
 	if vault.vaultPath != expectedPath {
 		t.Fatalf("Expected vault path %s, got %s", expectedPath, vault.vaultPath)
 	}
+	if vault.Path() != expectedPath {
+		t.Fatalf("Expected Path() %s, got %s", expectedPath, vault.Path())
+	}
 }
 
 func TestAddAndGetEntry(t *testing.T) {
 	vault, _ := createTestVault(t)
 	masterPassword := "test-master-password"
+	initTestVault(t, vault, masterPassword)
 
 	entry := models.PasswordEntry{
 		Username: "testuser",
@@ -75,6 +92,7 @@ func TestAddAndGetEntry(t *testing.T) {
 func TestAddMultipleEntries(t *testing.T) {
 	vault, _ := createTestVault(t)
 	masterPassword := "test-master-password"
+	initTestVault(t, vault, masterPassword)
 
 	entries := []models.PasswordEntry{
 		{Username: "user1", URL: "https://site1.com", Password: "pass1"},
@@ -120,6 +138,7 @@ func TestAddMultipleEntries(t *testing.T) {
 func TestSearchEntries(t *testing.T) {
 	vault, _ := createTestVault(t)
 	masterPassword := "test-master-password"
+	initTestVault(t, vault, masterPassword)
 
 	entries := []models.PasswordEntry{
 		{Username: "john@gmail.com", URL: "https://github.com", Password: "pass1"},
@@ -180,6 +199,7 @@ func TestWrongMasterPassword(t *testing.T) {
 	vault, _ := createTestVault(t)
 	correctPassword := "correct-password"
 	wrongPassword := "wrong-password"
+	initTestVault(t, vault, correctPassword)
 
 	entry := models.PasswordEntry{
 		Username: "testuser",
@@ -209,6 +229,7 @@ func TestWrongMasterPassword(t *testing.T) {
 func TestEmptyVault(t *testing.T) {
 	vault, _ := createTestVault(t)
 	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
 
 	// Get entries from empty vault
 	entries, err := vault.GetAllEntries(masterPassword)
@@ -234,6 +255,7 @@ func TestEmptyVault(t *testing.T) {
 func TestVaultPersistence(t *testing.T) {
 	vault, _ := createTestVault(t)
 	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
 
 	entry := models.PasswordEntry{
 		Username: "testuser",
@@ -248,7 +270,7 @@ func TestVaultPersistence(t *testing.T) {
 	}
 
 	// Create new vault instance with same path
-	vault2 := &VaultManager{vaultPath: vault.vaultPath}
+	vault2 := &VaultManager{vaultPath: vault.vaultPath, kdf: testKDFParams}
 
 	// Retrieve entries with new instance
 	entries, err := vault2.GetAllEntries(masterPassword)
@@ -264,3 +286,539 @@ func TestVaultPersistence(t *testing.T) {
 		t.Fatal("Entry not persisted correctly")
 	}
 }
+
+func TestUpgradeKDF(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+
+	// Create a vault protected by PBKDF2
+	vault.backups = 2
+	vault.kdf = crypto.KDFParams{Algorithm: crypto.KDFPBKDF2SHA256, Iterations: 1000}
+	initTestVault(t, vault, masterPassword)
+	if err := vault.AddEntry(models.PasswordEntry{Username: "testuser", Password: "secret"}, masterPassword); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	vault.kdf = testKDFParams
+
+	needsUpgrade, err := vault.NeedsKDFUpgrade()
+	if err != nil {
+		t.Fatalf("Failed to check KDF: %v", err)
+	}
+	if !needsUpgrade {
+		t.Fatal("PBKDF2 vault should need an upgrade")
+	}
+
+	// Declining the upgrade leaves the vault untouched
+	upgraded, err := vault.UpgradeKDF(masterPassword, func() (bool, error) { return false, nil })
+	if err != nil || upgraded {
+		t.Fatalf("Declined upgrade should not re-encrypt the vault (upgraded=%v, err=%v)", upgraded, err)
+	}
+	if needsUpgrade, _ := vault.NeedsKDFUpgrade(); !needsUpgrade {
+		t.Fatal("Declined upgrade should keep PBKDF2")
+	}
+
+	// Wrong password must fail before asking for confirmation
+	_, err = vault.UpgradeKDF("wrong-password", func() (bool, error) {
+		t.Fatal("confirm should not be called with a wrong password")
+		return false, nil
+	})
+	if err == nil {
+		t.Fatal("Upgrade with wrong password should fail")
+	}
+
+	upgraded, err = vault.UpgradeKDF(masterPassword, func() (bool, error) { return true, nil })
+	if err != nil || !upgraded {
+		t.Fatalf("Upgrade failed (upgraded=%v, err=%v)", upgraded, err)
+	}
+
+	header, err := vault.readHeader()
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	if header.KDF != testKDFParams {
+		t.Fatalf("Expected KDF params %+v, got %+v", testKDFParams, header.KDF)
+	}
+
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to read upgraded vault: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Password != "secret" {
+		t.Fatal("Entries should survive the KDF upgrade")
+	}
+
+	// No PBKDF2 copy of the vault is left in the backups
+	backups, _ := vault.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected the backup from before adding the entry, got %+v", backups)
+	}
+	backup := &VaultManager{vaultPath: backups[0].Path}
+	if header, err := backup.readHeader(); err != nil || header.KDF != testKDFParams {
+		t.Fatalf("Expected the backup to be re-encrypted with %+v, got %+v (%v)", testKDFParams, header, err)
+	}
+}
+
+func TestUpgradeKDFConfirmWithoutLock(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	vault.kdf = crypto.KDFParams{Algorithm: crypto.KDFPBKDF2SHA256, Iterations: 1000}
+	initTestVault(t, vault, masterPassword)
+	vault.kdf = testKDFParams
+
+	// Another process can use the vault while waiting for confirmation, and an upgrade it
+	// makes in the meantime is not repeated
+	upgraded, err := vault.UpgradeKDF(masterPassword, func() (bool, error) {
+		other := &VaultManager{vaultPath: vault.vaultPath, kdf: testKDFParams}
+		if upgraded, err := other.UpgradeKDF(masterPassword, func() (bool, error) { return true, nil }); err != nil || !upgraded {
+			t.Fatalf("Concurrent upgrade failed (upgraded=%v, err=%v)", upgraded, err)
+		}
+		return true, nil
+	})
+	if err != nil || upgraded {
+		t.Fatalf("Expected the concurrent upgrade to be noticed (upgraded=%v, err=%v)", upgraded, err)
+	}
+	if _, err := vault.GetAllEntries(masterPassword); err != nil {
+		t.Fatalf("Failed to read upgraded vault: %v", err)
+	}
+}
+
+func TestNeedsKDFUpgradeMissingVault(t *testing.T) {
+	vault, _ := createTestVault(t)
+
+	needsUpgrade, err := vault.NeedsKDFUpgrade()
+	if err != nil {
+		t.Fatalf("Missing vault should not be an error: %v", err)
+	}
+	if needsUpgrade {
+		t.Fatal("Missing vault should not need an upgrade")
+	}
+}
+
+func TestChangeMasterPassword(t *testing.T) {
+	vault, tempDir := createTestVault(t)
+	oldPassword := "old-password"
+	newPassword := "new-password"
+	initTestVault(t, vault, oldPassword)
+
+	if err := vault.AddEntry(models.PasswordEntry{Username: "testuser", Password: "secret"}, oldPassword); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	oldHeader, _ := vault.readHeader()
+
+	if err := vault.ChangeMasterPassword("wrong-password", newPassword); err == nil {
+		t.Fatal("Changing the password with a wrong current password should fail")
+	}
+	if err := vault.ChangeMasterPassword(oldPassword, ""); err == nil {
+		t.Fatal("Empty new password should be rejected")
+	}
+
+	if err := vault.ChangeMasterPassword(oldPassword, newPassword); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+
+	if _, err := vault.GetAllEntries(oldPassword); err == nil {
+		t.Fatal("Old password should no longer unlock the vault")
+	}
+
+	entries, err := vault.GetAllEntries(newPassword)
+	if err != nil {
+		t.Fatalf("New password should unlock the vault: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Password != "secret" {
+		t.Fatal("Entries should survive the password change")
+	}
+
+	newHeader, _ := vault.readHeader()
+	if string(newHeader.Salt) == string(oldHeader.Salt) {
+		t.Fatal("Changing the password should generate a fresh salt")
+	}
+
+	// No temporary files should be left behind
+	files, _ := os.ReadDir(tempDir)
+	for _, file := range files {
+		if strings.Contains(file.Name(), ".tmp-") {
+			t.Fatalf("Temporary file %s was left behind", file.Name())
+		}
+	}
+}
+
+func TestChangeMasterPasswordUnlocked(t *testing.T) {
+	vault, _ := createTestVault(t)
+	initTestVault(t, vault, "old-password")
+	if _, err := vault.Unlock("old-password"); err != nil {
+		t.Fatalf("Failed to unlock vault: %v", err)
+	}
+	if err := vault.ChangeMasterPassword("old-password", "new-password"); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+
+	// The manager keeps working with the new key, which a fresh unlock derives as well
+	if _, err := vault.GetAllEntries(""); err != nil {
+		t.Fatalf("Expected the manager to stay unlocked, got %v", err)
+	}
+	fresh := &VaultManager{vaultPath: vault.vaultPath}
+	key, err := fresh.Unlock("new-password")
+	if err != nil {
+		t.Fatalf("Failed to unlock with the new password: %v", err)
+	}
+	if !bytes.Equal(vault.Key(), key) {
+		t.Fatal("Expected the manager to hold the new key")
+	}
+}
+
+func TestChangeMasterPasswordMissingVault(t *testing.T) {
+	vault, _ := createTestVault(t)
+
+	if err := vault.ChangeMasterPassword("old", "new"); err == nil {
+		t.Fatal("Changing the password of a missing vault should fail")
+	}
+	if _, err := os.Stat(vault.vaultPath); !os.IsNotExist(err) {
+		t.Fatal("Changing the password of a missing vault should not create it")
+	}
+}
+
+func TestAddEntryAssignsID(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	for i := 0; i < 2; i++ {
+		if err := vault.AddEntry(models.PasswordEntry{Username: "same", URL: "https://same.com"}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	entries, _ := vault.GetAllEntries(masterPassword)
+	if entries[0].ID == "" || entries[1].ID == "" {
+		t.Fatal("Added entries should have an ID")
+	}
+	if entries[0].ID == entries[1].ID {
+		t.Fatal("Duplicate entries should still get different IDs")
+	}
+}
+
+func TestBackfillIDsIsPersisted(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	// Simulate a vault written before entries had IDs
+	unlocked, err := vault.loadVault(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to create vault: %v", err)
+	}
+	unlocked.Entries = []models.PasswordEntry{{Username: "old1"}, {Username: "old2"}}
+	if err := vault.saveVault(unlocked); err != nil {
+		t.Fatalf("Failed to save vault: %v", err)
+	}
+
+	first, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to get entries: %v", err)
+	}
+	second, _ := vault.GetAllEntries(masterPassword)
+
+	for i := range first {
+		if first[i].ID == "" {
+			t.Fatalf("Entry %d should have been given an ID", i)
+		}
+		if first[i].ID != second[i].ID {
+			t.Fatalf("Backfilled ID of entry %d changed between reads: %s != %s", i, first[i].ID, second[i].ID)
+		}
+	}
+}
+
+func TestGetEntry(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	for _, username := range []string{"user1", "user2"} {
+		if err := vault.AddEntry(models.PasswordEntry{Username: username}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	entries, _ := vault.GetAllEntries(masterPassword)
+
+	entry, err := vault.GetEntry(entries[1].ID, masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to get entry by ID: %v", err)
+	}
+	if entry.Username != "user2" {
+		t.Fatalf("Expected user2, got %s", entry.Username)
+	}
+
+	entry, err = vault.GetEntry(entries[0].ShortID(), masterPassword)
+	if err != nil {
+		t.Fatalf("Failed to get entry by short ID: %v", err)
+	}
+	if entry.Username != "user1" {
+		t.Fatalf("Expected user1, got %s", entry.Username)
+	}
+
+	if _, err := vault.GetEntry("does-not-exist", masterPassword); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Expected ErrEntryNotFound, got %v", err)
+	}
+	if _, err := vault.GetEntry("", masterPassword); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Expected ErrEntryNotFound for empty ID, got %v", err)
+	}
+}
+
+func TestUpdateEntry(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	// Two entries that only differ by password used to be indistinguishable
+	for _, password := range []string{"pass1", "pass2"} {
+		if err := vault.AddEntry(models.PasswordEntry{Username: "user", URL: "https://site.com", Password: password}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	entries, _ := vault.GetAllEntries(masterPassword)
+
+	updated := entries[1]
+	updated.Password = "changed"
+	if err := vault.UpdateEntry(updated, masterPassword); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+
+	after, _ := vault.GetAllEntries(masterPassword)
+	if after[0].Password != "pass1" {
+		t.Fatal("Updating one entry should not touch the other")
+	}
+	if after[1].Password != "changed" {
+		t.Fatalf("Expected updated password, got %s", after[1].Password)
+	}
+	if !after[1].CreatedAt.Equal(entries[1].CreatedAt) {
+		t.Fatal("CreatedAt should be preserved on update")
+	}
+	if !after[1].UpdatedAt.After(entries[1].UpdatedAt) {
+		t.Fatal("UpdatedAt should be refreshed on update")
+	}
+
+	updated.ID = updated.ShortID()
+	if err := vault.UpdateEntry(updated, masterPassword); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Update should require the full ID, got %v", err)
+	}
+}
+
+func TestUpdateEntryHistory(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	if err := vault.AddEntry(models.PasswordEntry{Username: "user", URL: "https://site.com", Password: "first"}, masterPassword); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	entries, _ := vault.GetAllEntries(masterPassword)
+	entry := entries[0]
+
+	entry.Password = "second"
+	if err := vault.UpdateEntry(entry, masterPassword); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+	// Changing only the username keeps the history as it is, even if the caller dropped it
+	entries, _ = vault.GetAllEntries(masterPassword)
+	entry = entries[0]
+	entry.Username = "renamed"
+	entry.History = nil
+	if err := vault.UpdateEntry(entry, masterPassword); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+
+	after, _ := vault.GetEntry(entry.ID, masterPassword)
+	if len(after.History) != 1 || after.History[0].Password != "first" {
+		t.Fatalf("Expected the first password in the history, got %+v", after.History)
+	}
+	if after.History[0].ReplacedAt.IsZero() {
+		t.Fatal("ReplacedAt should be set")
+	}
+	if after.Password != "second" || after.Username != "renamed" {
+		t.Fatalf("Unexpected entry after update: %+v", after)
+	}
+}
+
+func TestUpdateEntryChanged(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	if err := vault.AddEntry(models.PasswordEntry{Username: "user", URL: "https://site.com", Password: "first"}, masterPassword); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	entries, _ := vault.GetAllEntries(masterPassword)
+
+	// Another process changes the password after this one has read the entry
+	other := entries[0]
+	other.Password = "second"
+	if err := vault.UpdateEntry(other, masterPassword); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+
+	stale := entries[0]
+	stale.Username = "renamed"
+	if err := vault.UpdateEntry(stale, masterPassword); !errors.Is(err, ErrEntryChanged) {
+		t.Fatalf("Expected ErrEntryChanged, got %v", err)
+	}
+	after, _ := vault.GetEntry(stale.ID, masterPassword)
+	if after.Password != "second" || after.Username != "user" {
+		t.Fatalf("The concurrent change should be kept, got %+v", after)
+	}
+	if len(after.History) != 1 || after.History[0].Password != "first" {
+		t.Fatalf("Expected only the first password in the history, got %+v", after.History)
+	}
+}
+
+func TestUpdateEntries(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	for _, url := range []string{"https://a.com", "https://b.com"} {
+		if err := vault.AddEntry(models.PasswordEntry{Username: "user", URL: url}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	entries, _ := vault.GetAllEntries(masterPassword)
+
+	for i := range entries {
+		entries[i].Folder = "work"
+	}
+	if err := vault.UpdateEntries(entries, masterPassword); err != nil {
+		t.Fatalf("Failed to update entries: %v", err)
+	}
+	after, _ := vault.GetAllEntries(masterPassword)
+	if after[0].Folder != "work" || after[1].Folder != "work" {
+		t.Fatalf("Expected both entries to be moved, got %+v", after)
+	}
+
+	// A missing entry must leave the others untouched
+	after[0].Folder = "home"
+	missing := models.PasswordEntry{ID: "00000000-0000-4000-8000-000000000000"}
+	if err := vault.UpdateEntries([]models.PasswordEntry{after[0], missing}, masterPassword); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Expected ErrEntryNotFound, got %v", err)
+	}
+	if entry, _ := vault.GetEntry(after[0].ID, masterPassword); entry.Folder != "work" {
+		t.Fatalf("Expected no entry to change, got folder %q", entry.Folder)
+	}
+}
+
+func TestDeleteEntry(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+	initTestVault(t, vault, masterPassword)
+
+	// Duplicates share Username and URL; only the selected one must go
+	for i := 0; i < 3; i++ {
+		if err := vault.AddEntry(models.PasswordEntry{Username: "dup", URL: "https://dup.com"}, masterPassword); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	entries, _ := vault.GetAllEntries(masterPassword)
+
+	if err := vault.DeleteEntry(entries[1].ID, masterPassword); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+
+	after, _ := vault.GetAllEntries(masterPassword)
+	if len(after) != 2 {
+		t.Fatalf("Expected 2 entries after delete, got %d", len(after))
+	}
+	if after[0].ID != entries[0].ID || after[1].ID != entries[2].ID {
+		t.Fatal("The wrong entry was deleted")
+	}
+
+	if err := vault.DeleteEntry(entries[1].ID, masterPassword); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Deleting a missing entry should return ErrEntryNotFound, got %v", err)
+	}
+}
+
+func TestMissingVaultIsNotCreatedImplicitly(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+
+	if _, err := vault.GetAllEntries(masterPassword); !errors.Is(err, ErrVaultNotFound) {
+		t.Fatalf("Expected ErrVaultNotFound, got %v", err)
+	}
+	if err := vault.AddEntry(models.PasswordEntry{Username: "testuser"}, masterPassword); !errors.Is(err, ErrVaultNotFound) {
+		t.Fatalf("Expected ErrVaultNotFound, got %v", err)
+	}
+	if vault.Exists() {
+		t.Fatal("Operations on a missing vault should not create it")
+	}
+	if ErrVaultNotFound.Error() != "no vault found, run mpass init" {
+		t.Fatalf("Unexpected error message: %v", ErrVaultNotFound)
+	}
+}
+
+func TestCreateVault(t *testing.T) {
+	vault, _ := createTestVault(t)
+	masterPassword := "test-password"
+
+	if err := vault.Create(""); err == nil {
+		t.Fatal("Empty master password should be rejected")
+	}
+
+	if err := vault.Create(masterPassword); err != nil {
+		t.Fatalf("Failed to create vault: %v", err)
+	}
+	if !vault.Exists() {
+		t.Fatal("Vault file should exist after Create")
+	}
+
+	if err := vault.Create("other-password"); !errors.Is(err, ErrVaultExists) {
+		t.Fatalf("Creating over an existing vault should return ErrVaultExists, got %v", err)
+	}
+
+	entries, err := vault.GetAllEntries(masterPassword)
+	if err != nil {
+		t.Fatalf("Original password should still unlock the vault: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("New vault should be empty, got %d entries", len(entries))
+	}
+
+	header, _ := vault.readHeader()
+	if header.KDF != testKDFParams {
+		t.Fatalf("Expected KDF params %+v, got %+v", testKDFParams, header.KDF)
+	}
+}
+
+func TestUnlockAndUseKey(t *testing.T) {
+	vault, _ := createTestVault(t)
+	initTestVault(t, vault, "master-password")
+
+	if _, err := vault.Unlock("wrong-password"); err == nil {
+		t.Fatal("Expected Unlock to fail with the wrong master password")
+	}
+
+	key, err := vault.Unlock("master-password")
+	if err != nil {
+		t.Fatalf("Failed to unlock vault: %v", err)
+	}
+
+	// Once unlocked, operations no longer need the master password
+	if err := vault.AddEntry(models.PasswordEntry{Username: "alice", Password: "secret"}, ""); err != nil {
+		t.Fatalf("Failed to add entry with unlocked vault: %v", err)
+	}
+
+	other := NewVault(vault.Path())
+	if err := other.UseKey([]byte("0123456789abcdef0123456789abcdef")); err == nil {
+		t.Fatal("Expected UseKey to fail with the wrong key")
+	}
+	if _, err := other.GetAllEntries(""); err == nil {
+		t.Fatal("Expected a manager with a rejected key to stay locked")
+	}
+
+	if err := other.UseKey(key); err != nil {
+		t.Fatalf("Failed to use key: %v", err)
+	}
+	entries, err := other.GetAllEntries("")
+	if err != nil {
+		t.Fatalf("Failed to read entries with key: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Username != "alice" {
+		t.Fatalf("Expected the entry added with the unlocked vault, got %+v", entries)
+	}
+}
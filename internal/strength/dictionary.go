@@ -0,0 +1,101 @@
+package strength
+
+import (
+	"bufio"
+	_ "embed"
+	"mpass/internal/passphrase"
+	"strings"
+	"sync"
+)
+
+// commonPasswords lists frequently used passwords, most common first.
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// dictionary maps lower case words to their rank: roughly how many guesses an attacker
+// trying the words in order of popularity needs to reach them.
+type dictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+// Dictionary names, used to explain a match.
+const (
+	passwordsDictionary = "passwords"
+	englishDictionary   = "english"
+)
+
+// dictionaries returns the ranked dictionaries: common passwords by popularity and the
+// English words of the EFF large wordlist. The wordlist is not ordered by frequency, so
+// every word gets the rank of half the list, the average cost of finding a word in it.
+var dictionaries = sync.OnceValue(func() []dictionary {
+	passwords := dictionary{name: passwordsDictionary, ranks: map[string]int{}}
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswords))
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			if _, ok := passwords.ranks[word]; !ok {
+				passwords.ranks[word] = len(passwords.ranks) + 1
+			}
+		}
+	}
+
+	words := passphrase.Wordlist()
+	english := dictionary{name: englishDictionary, ranks: make(map[string]int, len(words))}
+	for _, word := range words {
+		english.ranks[word] = len(words) / 2
+	}
+	return []dictionary{passwords, english}
+})
+
+// l33tTable maps characters used in l33t speak to the letters they can stand for.
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'7': {'l', 't'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'+': {'t'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// maxL33tSubstitutions bounds how many ways of reading the l33t characters are tried.
+const maxL33tSubstitutions = 64
+
+// l33tSubstitutions returns the possible readings of the l33t characters in password,
+// each mapping a l33t character to a letter.
+func l33tSubstitutions(password []rune) []map[rune]rune {
+	subs := []map[rune]rune{{}}
+	seen := map[rune]bool{}
+	for _, r := range password {
+		letters, ok := l33tTable[r]
+		if !ok || seen[r] {
+			continue
+		}
+		seen[r] = true
+
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range letters {
+				if len(next) == maxL33tSubstitutions {
+					break
+				}
+				extended := map[rune]rune{r: letter}
+				for k, v := range sub {
+					extended[k] = v
+				}
+				next = append(next, extended)
+			}
+		}
+		subs = next
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	return subs
+}
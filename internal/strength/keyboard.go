@@ -0,0 +1,69 @@
+package strength
+
+import "strings"
+
+// qwertyRows describes a US QWERTY keyboard, each key as its unshifted and shifted character.
+// Every row is shifted right by about half a key from the one above, which makes the keys at
+// the same and the next column of the row above adjacent.
+var qwertyRows = [][]string{
+	strings.Fields("`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+"),
+	strings.Fields("qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|"),
+	strings.Fields("aA sS dD fF gG hH jJ kK lL ;: '\""),
+	strings.Fields("zZ xX cC vV bB nN mM ,< .> /?"),
+}
+
+// keyboard maps every character of the layout to its neighbours, one entry per direction in
+// a fixed order so that a change of direction can be detected. Missing neighbours are "".
+type keyboard map[rune][]string
+
+// qwerty is the adjacency graph of qwertyRows.
+var qwerty = buildKeyboard(qwertyRows)
+
+// buildKeyboard returns the adjacency graph of a keyboard layout given as rows of keys.
+func buildKeyboard(rows [][]string) keyboard {
+	key := func(row, col int) string {
+		if row < 0 || row >= len(rows) || col < 0 || col >= len(rows[row]) {
+			return ""
+		}
+		return rows[row][col]
+	}
+
+	graph := keyboard{}
+	for row, keys := range rows {
+		for col, k := range keys {
+			neighbours := []string{
+				key(row, col-1), key(row-1, col), key(row-1, col+1),
+				key(row, col+1), key(row+1, col), key(row+1, col-1),
+			}
+			for _, r := range k {
+				graph[r] = neighbours
+			}
+		}
+	}
+	return graph
+}
+
+// averageDegree returns the average number of neighbours of a key.
+func (k keyboard) averageDegree() float64 {
+	total := 0
+	for _, neighbours := range k {
+		for _, n := range neighbours {
+			if n != "" {
+				total++
+			}
+		}
+	}
+	return float64(total) / float64(len(k))
+}
+
+// isShifted reports whether r is typed with shift on the layout.
+func isShifted(r rune) bool {
+	for _, row := range qwertyRows {
+		for _, k := range row {
+			if []rune(k)[1] == r {
+				return true
+			}
+		}
+	}
+	return false
+}
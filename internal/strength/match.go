@@ -0,0 +1,525 @@
+package strength
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kinds of patterns a part of a password can match.
+const (
+	patternCommonPassword = "common password"
+	patternDictionary     = "dictionary word"
+	patternL33t           = "l33t"
+	patternReversed       = "reversed word"
+	patternSpatial        = "keyboard pattern"
+	patternRepeat         = "repeat"
+	patternSequence       = "sequence"
+	patternDate           = "date"
+)
+
+// Minimum guesses of a match, as in zxcvbn: nothing is cheaper than trying a few characters.
+const (
+	minGuessesSingleChar = 10
+	minGuessesMultiChar  = 50
+)
+
+// maxWordLength bounds the length of the substrings looked up in the dictionaries.
+const maxWordLength = 40
+
+// match is a part of a password, from rune i to rune j inclusive, that follows a pattern
+// an attacker would try, with the number of guesses needed to find it that way.
+type match struct {
+	i, j    int
+	pattern string
+	guesses float64
+}
+
+// findMatches returns every pattern match found in password.
+func findMatches(password []rune) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(password)...)
+	matches = append(matches, reversedMatches(password)...)
+	matches = append(matches, l33tMatches(password)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, repeatMatches(password)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+
+	for k := range matches {
+		floor := float64(minGuessesMultiChar)
+		if matches[k].i == matches[k].j {
+			floor = minGuessesSingleChar
+		}
+		matches[k].guesses = math.Max(matches[k].guesses, floor)
+	}
+	return matches
+}
+
+// binomial returns n choose k.
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// uppercaseVariations returns how many ways of capitalizing word an attacker tries before
+// finding its capitalization: all lower case first, then a capital first or last letter or
+// all capitals, then every mix with the same number of capitals.
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	first, last := unicode.IsUpper(word[0]), unicode.IsUpper(word[len(word)-1])
+	if lower == 0 || (upper == 1 && (first || last)) {
+		return 2
+	}
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// lookupWords returns the dictionary matches in lower, the lower case password.
+// Only substrings for which accept returns true are considered.
+func lookupWords(lower []rune, accept func(i, j int) bool) []match {
+	var matches []match
+	for i := range lower {
+		for j := i; j < len(lower) && j-i < maxWordLength; j++ {
+			if !accept(i, j) {
+				continue
+			}
+			word := string(lower[i : j+1])
+			for _, dict := range dictionaries() {
+				rank, ok := dict.ranks[word]
+				if !ok {
+					continue
+				}
+				pattern := patternDictionary
+				if dict.name == passwordsDictionary {
+					pattern = patternCommonPassword
+				}
+				matches = append(matches, match{i: i, j: j, pattern: pattern, guesses: float64(rank)})
+			}
+		}
+	}
+	return matches
+}
+
+// toLower returns password with every letter in lower case, rune for rune.
+func toLower(password []rune) []rune {
+	lower := make([]rune, len(password))
+	for i, r := range password {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// dictionaryMatches finds common passwords and dictionary words in password.
+func dictionaryMatches(password []rune) []match {
+	matches := lookupWords(toLower(password), func(int, int) bool { return true })
+	for k := range matches {
+		matches[k].guesses *= uppercaseVariations(password[matches[k].i : matches[k].j+1])
+	}
+	return matches
+}
+
+// reversedMatches finds dictionary words written backwards in password, such as "drowssap".
+func reversedMatches(password []rune) []match {
+	n := len(password)
+	reversed := make([]rune, n)
+	for i, r := range password {
+		reversed[n-1-i] = r
+	}
+
+	matches := lookupWords(toLower(reversed), func(i, j int) bool { return j > i })
+	for k, m := range matches {
+		matches[k] = match{
+			i:       n - 1 - m.j,
+			j:       n - 1 - m.i,
+			pattern: patternReversed,
+			guesses: m.guesses * uppercaseVariations(reversed[m.i:m.j+1]) * 2,
+		}
+	}
+	return matches
+}
+
+// l33tMatches finds dictionary words written with l33t substitutions, such as "p@ssw0rd".
+func l33tMatches(password []rune) []match {
+	var matches []match
+	lower := toLower(password)
+	for _, sub := range l33tSubstitutions(password) {
+		translated := make([]rune, len(lower))
+		for i, r := range lower {
+			if letter, ok := sub[password[i]]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+
+		substituted := func(i, j int) bool {
+			for k := i; k <= j; k++ {
+				if _, ok := sub[password[k]]; ok {
+					return j > i
+				}
+			}
+			return false
+		}
+		for _, m := range lookupWords(translated, substituted) {
+			token := password[m.i : m.j+1]
+			m.pattern = patternL33t
+			m.guesses *= uppercaseVariations(token) * l33tVariations(token, sub)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// l33tVariations returns how many ways of substituting the letters of token an attacker
+// tries: for every substituted letter, every choice of which of its occurrences are
+// substituted, or 2 if all or none of them are.
+func l33tVariations(token []rune, sub map[rune]rune) float64 {
+	variations := 1.0
+	for l33t, letter := range sub {
+		subbed, unsubbed := 0, 0
+		for _, r := range token {
+			switch {
+			case r == l33t:
+				subbed++
+			case unicode.ToLower(r) == letter:
+				unsubbed++
+			}
+		}
+		if subbed == 0 {
+			continue
+		}
+		if unsubbed == 0 {
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= min(subbed, unsubbed); i++ {
+			possibilities += binomial(subbed+unsubbed, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+// spatialMatches finds runs of at least three adjacent keys on a QWERTY keyboard, such as
+// "qwerty" or "zxcvb".
+func spatialMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password)-1; {
+		j := i + 1
+		lastDirection, turns, shifted := -1, 0, 0
+		if isShifted(password[i]) {
+			shifted++
+		}
+		for ; j < len(password); j++ {
+			direction := -1
+			for dir, key := range qwerty[password[j-1]] {
+				if key == "" {
+					continue
+				}
+				if k := []rune(key); k[0] == password[j] || k[1] == password[j] {
+					direction = dir
+					if k[1] == password[j] {
+						shifted++
+					}
+					break
+				}
+			}
+			if direction < 0 {
+				break
+			}
+			if direction != lastDirection {
+				turns++
+				lastDirection = direction
+			}
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{i: i, j: j - 1, pattern: patternSpatial, guesses: spatialGuesses(j-i, turns, shifted)})
+		}
+		i = j
+	}
+	return matches
+}
+
+// spatialGuesses estimates the guesses for a keyboard pattern of the given length, number of
+// changes of direction and shifted keys, following zxcvbn.
+func spatialGuesses(length, turns, shifted int) float64 {
+	starts := float64(len(qwerty))
+	degree := qwerty.averageDegree()
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * starts * math.Pow(degree, float64(j))
+		}
+	}
+
+	unshifted := length - shifted
+	if shifted > 0 {
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= min(shifted, unshifted); i++ {
+				variations += binomial(shifted+unshifted, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+// repeatMatches finds a character or block repeated at least twice in a row, such as "aaa"
+// or "abcabc". The guesses are those of the block times the number of repetitions.
+func repeatMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password); {
+		bestLen, bestBase := 0, 0
+		for base := 1; i+2*base <= len(password); base++ {
+			count := 1
+			for i+(count+1)*base <= len(password) && string(password[i+count*base:i+(count+1)*base]) == string(password[i:i+base]) {
+				count++
+			}
+			if count >= 2 && count*base > bestLen {
+				bestLen, bestBase = count*base, base
+			}
+		}
+		if bestLen == 0 {
+			i++
+			continue
+		}
+		base := Estimate(string(password[i : i+bestBase])).Guesses
+		matches = append(matches, match{
+			i:       i,
+			j:       i + bestLen - 1,
+			pattern: patternRepeat,
+			guesses: base * float64(bestLen/bestBase),
+		})
+		i += bestLen
+	}
+	return matches
+}
+
+// sequenceClass returns the class of characters a sequence can run through, or 0.
+func sequenceClass(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 'a'
+	case r >= 'A' && r <= 'Z':
+		return 'A'
+	case r >= '0' && r <= '9':
+		return '0'
+	}
+	return 0
+}
+
+// sequenceMatches finds runs of at least three characters with the same small step, such as
+// "abcd", "9876" or "acegi".
+func sequenceMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password)-2; {
+		delta := password[i+1] - password[i]
+		j := i + 1
+		for j+1 < len(password) && password[j+1]-password[j] == delta {
+			j++
+		}
+
+		class := sequenceClass(password[i])
+		sameClass := class != 0
+		for k := i; k <= j; k++ {
+			sameClass = sameClass && sequenceClass(password[k]) == class
+		}
+		if j-i >= 2 && sameClass && delta != 0 && delta >= -5 && delta <= 5 {
+			matches = append(matches, match{i: i, j: j, pattern: patternSequence, guesses: sequenceGuesses(password[i], j-i+1, delta < 0)})
+		}
+		i = j
+	}
+	return matches
+}
+
+// sequenceGuesses estimates the guesses for a sequence: obvious starting points such as "a"
+// or "1" are tried first, and descending sequences after ascending ones.
+func sequenceGuesses(first rune, length int, descending bool) float64 {
+	var base float64
+	switch {
+	case first == 'a' || first == 'A' || first == 'z' || first == 'Z' || first == '0' || first == '1' || first == '9':
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if descending {
+		base *= 2
+	}
+	return base * float64(length)
+}
+
+// Dates are looked for between these years.
+const (
+	minDateYear = 1000
+	maxDateYear = 2050
+)
+
+// minYearSpace keeps dates close to the current year from being counted as almost free.
+const minYearSpace = 20
+
+// referenceYear is the year recent dates are measured from.
+var referenceYear = time.Now().Year()
+
+// dateSeparatorPattern matches dates such as 13/05/1990, 1990-05-13 or 5.13.90.
+var dateSeparatorPattern = regexp.MustCompile(`(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})`)
+
+// yearPattern matches years from 1900 to 2099.
+var yearPattern = regexp.MustCompile(`(?:19|20)\d\d`)
+
+// dateSplits lists, by number of digits, where a date without separators can be split
+// into its three parts.
+var dateSplits = map[int][][2]int{
+	4: {{1, 2}, {2, 3}},
+	5: {{1, 3}, {2, 3}},
+	6: {{1, 2}, {2, 4}, {4, 5}},
+	7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+	8: {{2, 4}, {4, 6}},
+}
+
+// dateYear returns the year of a date made of the three given parts, in either day-month-year,
+// month-day-year, year-month-day or year-day-month order, and false if they are not a date.
+func dateYear(parts [3]string) (int, bool) {
+	best, found := 0, false
+	try := func(year string, a, b string) {
+		y, ok := parseYear(year)
+		if !ok {
+			return
+		}
+		x, _ := strconv.Atoi(a)
+		z, _ := strconv.Atoi(b)
+		if !(validDayMonth(x, z) || validDayMonth(z, x)) {
+			return
+		}
+		if !found || abs(y-referenceYear) < abs(best-referenceYear) {
+			best, found = y, true
+		}
+	}
+	try(parts[2], parts[0], parts[1])
+	try(parts[0], parts[1], parts[2])
+	return best, found
+}
+
+// parseYear reads a two or four digit year; two digit years are placed in 1951 to 2050.
+func parseYear(s string) (int, bool) {
+	y, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	switch len(s) {
+	case 2:
+		if y > 50 {
+			return 1900 + y, true
+		}
+		return 2000 + y, true
+	case 4:
+		return y, y >= minDateYear && y <= maxDateYear
+	}
+	return 0, false
+}
+
+// validDayMonth reports whether day and month can be a day of that month.
+func validDayMonth(day, month int) bool {
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// dateGuesses estimates the guesses for a date in the given year: a day of a year near the
+// current one, four times as many if the parts are separated.
+func dateGuesses(year int, separated bool) float64 {
+	guesses := float64(max(abs(year-referenceYear), minYearSpace)) * 365
+	if separated {
+		guesses *= 4
+	}
+	return guesses
+}
+
+// dateMatches finds dates with and without separators and bare years in password.
+func dateMatches(password []rune) []match {
+	s := string(password)
+	runeIndex := func(byteIndex int) int {
+		return utf8.RuneCountInString(s[:byteIndex])
+	}
+
+	var matches []match
+	for _, loc := range yearPattern.FindAllStringIndex(s, -1) {
+		year, _ := strconv.Atoi(s[loc[0]:loc[1]])
+		guesses := float64(max(abs(year-referenceYear), minYearSpace))
+		matches = append(matches, match{i: runeIndex(loc[0]), j: runeIndex(loc[1]) - 1, pattern: patternDate, guesses: guesses})
+	}
+
+	for _, loc := range dateSeparatorPattern.FindAllStringSubmatchIndex(s, -1) {
+		if s[loc[4]:loc[5]] != s[loc[8]:loc[9]] {
+			continue // both separators must be the same
+		}
+		parts := [3]string{s[loc[2]:loc[3]], s[loc[6]:loc[7]], s[loc[10]:loc[11]]}
+		if year, ok := dateYear(parts); ok {
+			matches = append(matches, match{i: runeIndex(loc[0]), j: runeIndex(loc[1]) - 1, pattern: patternDate, guesses: dateGuesses(year, true)})
+		}
+	}
+
+	for i := range password {
+		for j := i + 3; j < len(password) && j-i < 8; j++ {
+			digits := string(password[i : j+1])
+			if !isDigits(digits) {
+				break
+			}
+			best, found := 0, false
+			for _, split := range dateSplits[len(digits)] {
+				parts := [3]string{digits[:split[0]], digits[split[0]:split[1]], digits[split[1]:]}
+				if year, ok := dateYear(parts); ok && (!found || abs(year-referenceYear) < abs(best-referenceYear)) {
+					best, found = year, true
+				}
+			}
+			if found {
+				matches = append(matches, match{i: i, j: j, pattern: patternDate, guesses: dateGuesses(best, false)})
+			}
+		}
+	}
+	return matches
+}
+
+// isDigits reports whether s consists of ASCII digits only.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
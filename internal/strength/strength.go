@@ -0,0 +1,178 @@
+// Package strength estimates how many guesses an attacker needs for a password, in the manner
+// of zxcvbn: by matching it against common passwords, words, keyboard patterns and sequences.
+package strength
+
+import (
+	"fmt"
+	"math"
+	"unicode"
+)
+
+// Score thresholds on the estimated number of guesses, following zxcvbn.
+var scoreThresholds = []float64{1e3, 1e6, 1e8, 1e10}
+
+// maxLength is the number of characters of a password that are analyzed. Matching takes cubic
+// time in the length, and like zxcvbn anything beyond this adds nothing to the score anyway.
+const maxLength = 100
+
+// scoreLabels describes each score from 0 to 4.
+var scoreLabels = []string{"very weak", "weak", "fair", "strong", "very strong"}
+
+// warnings explains each kind of pattern to the user.
+var warnings = map[string]string{
+	patternCommonPassword: "this is a commonly used password",
+	patternDictionary:     "contains a common word",
+	patternL33t:           "contains a common word; substitutions like '@' for 'a' do not help much",
+	patternReversed:       "contains a common word written backwards",
+	patternSpatial:        "contains a keyboard pattern",
+	patternRepeat:         "contains repeated characters",
+	patternSequence:       "contains a sequence such as abc or 6543",
+	patternDate:           "contains a date or year",
+}
+
+// Result is the outcome of a password strength estimate.
+type Result struct {
+	Score   int     // 0 (too guessable) to 4 (very unguessable)
+	Guesses float64 // estimated number of guesses an attacker needs
+	Warning string  // the most significant guessable pattern found, if any
+}
+
+// Estimate estimates how hard password is to guess, in the manner of zxcvbn. The password is
+// split into the parts that are cheapest for an attacker to guess: common passwords, dictionary
+// words (also reversed or with l33t substitutions), keyboard patterns, repeats, sequences and
+// dates, with brute force over the character classes used for anything else. Only the first
+// maxLength characters are analyzed.
+func Estimate(password string) Result {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Result{Score: 0, Guesses: 1}
+	}
+	if len(runes) > maxLength {
+		runes = runes[:maxLength]
+	}
+
+	guesses, matches := mostGuessableSequence(runes, findMatches(runes))
+	result := Result{Score: scoreFor(guesses), Guesses: guesses}
+
+	longest := -1
+	for _, m := range matches {
+		if m.pattern != "" && m.j-m.i > longest {
+			longest = m.j - m.i
+			result.Warning = warnings[m.pattern]
+		}
+	}
+	return result
+}
+
+// mostGuessableSequence returns the fewest guesses needed to find password by combining the
+// matches and brute force for the parts in between, and the matches used. Brute force draws
+// from the character classes of the whole password, so splitting it gains nothing. As in zxcvbn, a
+// sequence of k parts costs k! times the product of their guesses, since the attacker does
+// not know the order of the parts. Brute force parts have an empty pattern.
+func mostGuessableSequence(password []rune, matches []match) (float64, []match) {
+	n := len(password)
+	logPool := math.Log10(bruteForcePool(string(password)))
+	byEnd := make([][]match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	// best[k][j] is the log10 of the fewest guesses for password[:j] as k parts, and
+	// last[k][j] the final part of that sequence.
+	best := make([][]float64, n+1)
+	last := make([][]match, n+1)
+	for k := range best {
+		best[k] = make([]float64, n+1)
+		last[k] = make([]match, n+1)
+		for j := range best[k] {
+			best[k][j] = math.Inf(1)
+		}
+	}
+	best[0][0] = 0
+
+	for j := 1; j <= n; j++ {
+		for k := 1; k <= j; k++ {
+			for i := 0; i < j; i++ {
+				if cost := best[k-1][i] + float64(j-i)*logPool; cost < best[k][j] {
+					best[k][j] = cost
+					last[k][j] = match{i: i, j: j - 1}
+				}
+			}
+			for _, m := range byEnd[j-1] {
+				if cost := best[k-1][m.i] + math.Log10(m.guesses); cost < best[k][j] {
+					best[k][j] = cost
+					last[k][j] = m
+				}
+			}
+		}
+	}
+
+	bestK, bestLog := 0, math.Inf(1)
+	for k := 1; k <= n; k++ {
+		total := best[k][n]
+		for f := 2; f <= k; f++ {
+			total += math.Log10(float64(f))
+		}
+		if total < bestLog {
+			bestK, bestLog = k, total
+		}
+	}
+
+	var sequence []match
+	for k, j := bestK, n; k > 0; k-- {
+		m := last[k][j]
+		sequence = append([]match{m}, sequence...)
+		j = m.i
+	}
+	return math.Pow(10, bestLog), sequence
+}
+
+// Label returns a short description of the score, such as "weak" or "strong".
+func (r Result) Label() string {
+	return scoreLabels[r.Score]
+}
+
+// String returns the label, score and order of magnitude of the guesses.
+func (r Result) String() string {
+	return fmt.Sprintf("%s (score %d/4, ~10^%.0f guesses)", r.Label(), r.Score, math.Log10(math.Max(r.Guesses, 1)))
+}
+
+// scoreFor maps a number of guesses to a score from 0 to 4.
+func scoreFor(guesses float64) int {
+	for score, threshold := range scoreThresholds {
+		if guesses < threshold {
+			return score
+		}
+	}
+	return len(scoreThresholds)
+}
+
+// bruteForcePool returns the number of characters in the classes password contains.
+func bruteForcePool(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r) && r < unicode.MaxASCII:
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	return float64(pool)
+}
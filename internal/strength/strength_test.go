@@ -0,0 +1,127 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateScores(t *testing.T) {
+	tests := []struct {
+		password string
+		score    int
+	}{
+		{"", 0},
+		{"qz", 0},
+		{"qzxw", 1},
+		{"qzxwk", 2},
+		{"qzxwkq", 3},
+		{"Tr0ub4dour&3", 4},
+	}
+
+	for _, tt := range tests {
+		result := Estimate(tt.password)
+		if result.Score != tt.score {
+			t.Fatalf("Expected score %d for %q, got %d (%s)", tt.score, tt.password, result.Score, result)
+		}
+	}
+}
+
+func TestEstimateMoreClassesIsStronger(t *testing.T) {
+	lower := Estimate("abcdefgh")
+	mixed := Estimate("abcDEF1!")
+
+	if mixed.Guesses <= lower.Guesses {
+		t.Fatalf("Mixed classes should need more guesses: %g <= %g", mixed.Guesses, lower.Guesses)
+	}
+}
+
+func TestEstimateLongPassword(t *testing.T) {
+	// Without truncation this takes minutes
+	password := strings.Repeat("x9#Kq", 2000)
+	if got, want := Estimate(password), Estimate(password[:maxLength]); got != want {
+		t.Fatalf("Expected only the first %d characters to count, got %s and %s", maxLength, got, want)
+	}
+}
+
+func TestResultLabel(t *testing.T) {
+	if (Result{Score: 0}).Label() != "very weak" {
+		t.Fatal("Score 0 should be very weak")
+	}
+	if (Result{Score: 4}).Label() != "very strong" {
+		t.Fatal("Score 4 should be very strong")
+	}
+}
+
+func TestEstimatePatterns(t *testing.T) {
+	tests := []struct {
+		password string
+		warning  string
+	}{
+		{"password", "commonly used password"},
+		{"P@ssw0rd", "substitutions"},
+		{"drowssap", "backwards"},
+		{"qwertyuiop", "commonly used password"},
+		{"xcvbnmasdf", "keyboard pattern"},
+		{"aaaaaaaaaaaa", "repeated characters"},
+		{"abcdefghijk", "sequence"},
+		{"13/05/1990", "date"},
+		{"Jennifer1987", "commonly used password"},
+		{"abacus", "common word"},
+	}
+
+	for _, tt := range tests {
+		result := Estimate(tt.password)
+		if result.Score > 1 {
+			t.Fatalf("Expected %q to be weak, got %s", tt.password, result)
+		}
+		if !strings.Contains(result.Warning, tt.warning) {
+			t.Fatalf("Expected a warning about %q for %q, got %q", tt.warning, tt.password, result.Warning)
+		}
+	}
+}
+
+func TestEstimateRandomIsStrong(t *testing.T) {
+	for _, password := range []string{"x7#Kp2$vLq9@", "Tr0ub4dour&3", "correcthorsebatterystaple"} {
+		if result := Estimate(password); result.Score != 4 {
+			t.Fatalf("Expected %q to be very strong, got %s", password, result)
+		}
+	}
+	if warning := Estimate("x7#Kp2$vLq9@").Warning; warning != "" {
+		t.Fatalf("Expected no warning for a random password, got %q", warning)
+	}
+}
+
+func TestUppercaseVariations(t *testing.T) {
+	tests := []struct {
+		word       string
+		variations float64
+	}{
+		{"password", 1},
+		{"Password", 2},
+		{"passworD", 2},
+		{"PASSWORD", 2},
+		{"PaSsword", 36},
+	}
+	for _, tt := range tests {
+		if got := uppercaseVariations([]rune(tt.word)); got != tt.variations {
+			t.Fatalf("Expected %g variations for %q, got %g", tt.variations, tt.word, got)
+		}
+	}
+}
+
+func TestDateMatches(t *testing.T) {
+	for _, password := range []string{"1990", "13051990", "1990-05-13", "5.13.90"} {
+		found := false
+		for _, m := range dateMatches([]rune(password)) {
+			found = found || (m.i == 0 && m.j == len(password)-1)
+		}
+		if !found {
+			t.Fatalf("Expected %q to be matched as a date", password)
+		}
+	}
+	for _, m := range dateMatches([]rune("99/99/9999")) {
+		if m.i == 0 {
+			t.Fatalf("Expected 99/99/9999 not to be matched as a date, got %+v", m)
+		}
+	}
+}
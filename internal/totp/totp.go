@@ -0,0 +1,172 @@
+// Package totp computes time-based one-time passwords (RFC 6238) from secrets given as
+// otpauth:// URIs or plain base32 strings.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used by authenticator apps when a key does not say otherwise.
+const (
+	DefaultAlgorithm = "SHA1"
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+)
+
+// algorithms maps the supported algorithm names to their hash functions.
+var algorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// Key is a TOTP secret with its parameters. Zero values stand for the defaults.
+type Key struct {
+	Secret    string `json:"secret"`              // base32, upper case without padding
+	Algorithm string `json:"algorithm,omitempty"` // SHA1, SHA256 or SHA512
+	Digits    int    `json:"digits,omitempty"`    // 6 to 10
+	Period    int    `json:"period,omitempty"`    // seconds each code is valid
+}
+
+// Parse reads a key from an otpauth://totp/ URI, taking the secret, algorithm, digits and
+// period from its query, or from a plain base32 secret, in which spaces, dashes and
+// padding are ignored.
+func Parse(s string) (*Key, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(strings.ToLower(s), "otpauth:") {
+		key := &Key{Secret: s}
+		return key, key.Normalize()
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return nil, fmt.Errorf("unsupported one-time password type %q, only totp is supported", u.Host)
+	}
+	query := u.Query()
+	key := &Key{Secret: query.Get("secret"), Algorithm: query.Get("algorithm")}
+	if digits := query.Get("digits"); digits != "" {
+		if key.Digits, err = strconv.Atoi(digits); err != nil || key.Digits <= 0 {
+			return nil, fmt.Errorf("invalid digits %q in otpauth URI", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		if key.Period, err = strconv.Atoi(period); err != nil || key.Period <= 0 {
+			return nil, fmt.Errorf("invalid period %q in otpauth URI", period)
+		}
+	}
+	return key, key.Normalize()
+}
+
+// Normalize puts the secret and algorithm in canonical form, clears parameters set to
+// their defaults and validates the key.
+func (k *Key) Normalize() error {
+	k.Secret = strings.TrimRight(strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(k.Secret)), "=")
+	k.Algorithm = strings.ToUpper(strings.ReplaceAll(k.Algorithm, "-", ""))
+	if k.Algorithm == DefaultAlgorithm {
+		k.Algorithm = ""
+	}
+	if k.Digits == DefaultDigits {
+		k.Digits = 0
+	}
+	if k.Period == DefaultPeriod {
+		k.Period = 0
+	}
+	return k.Validate()
+}
+
+// Validate checks that the secret is valid base32 and the parameters are supported.
+func (k *Key) Validate() error {
+	if k.Secret == "" {
+		return errors.New("TOTP secret cannot be empty")
+	}
+	if _, err := k.secret(); err != nil {
+		return err
+	}
+	if _, ok := algorithms[k.algorithm()]; !ok {
+		return fmt.Errorf("unsupported TOTP algorithm %q (available: SHA1, SHA256, SHA512)", k.Algorithm)
+	}
+	if digits := k.digits(); digits < 6 || digits > 10 {
+		return fmt.Errorf("TOTP codes must have 6 to 10 digits, got %d", digits)
+	}
+	if k.period() <= 0 {
+		return fmt.Errorf("TOTP period must be positive, got %d", k.Period)
+	}
+	return nil
+}
+
+// secret decodes the base32 secret.
+func (k *Key) secret() ([]byte, error) {
+	s := k.Secret
+	if n := len(s) % 8; n != 0 {
+		s += strings.Repeat("=", 8-n)
+	}
+	secret, err := base32.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("TOTP secret is not valid base32")
+	}
+	return secret, nil
+}
+
+func (k *Key) algorithm() string {
+	if k.Algorithm == "" {
+		return DefaultAlgorithm
+	}
+	return k.Algorithm
+}
+
+func (k *Key) digits() int {
+	if k.Digits == 0 {
+		return DefaultDigits
+	}
+	return k.Digits
+}
+
+func (k *Key) period() int {
+	if k.Period == 0 {
+		return DefaultPeriod
+	}
+	return k.Period
+}
+
+// Code returns the code valid at time t.
+func (k *Key) Code(t time.Time) (string, error) {
+	if err := k.Validate(); err != nil {
+		return "", err
+	}
+	secret, _ := k.secret()
+
+	mac := hmac.New(algorithms[k.algorithm()], secret)
+	binary.Write(mac, binary.BigEndian, uint64(t.Unix()/int64(k.period())))
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226, section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+	digits := k.digits()
+	modulus := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulus), nil
+}
+
+// Remaining returns how long the code valid at time t stays valid.
+func (k *Key) Remaining(t time.Time) time.Duration {
+	period := time.Duration(k.period()) * time.Second
+	return period - time.Duration(t.UnixNano())%period
+}
@@ -0,0 +1,115 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238, appendix B: the seeds are ASCII digits of the hash's block-friendly length.
+var (
+	seedSHA1   = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	seedSHA256 = base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	seedSHA512 = base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+)
+
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		for _, c := range []struct {
+			key  Key
+			want string
+		}{
+			{Key{Secret: seedSHA1, Digits: 8}, tt.sha1},
+			{Key{Secret: seedSHA256, Algorithm: "SHA256", Digits: 8}, tt.sha256},
+			{Key{Secret: seedSHA512, Algorithm: "SHA512", Digits: 8}, tt.sha512},
+		} {
+			got, err := c.key.Code(at)
+			if err != nil {
+				t.Fatalf("Failed to compute code: %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("Expected %s for %s at %d, got %s", c.want, c.key.algorithm(), tt.unix, got)
+			}
+		}
+	}
+}
+
+func TestParseSecret(t *testing.T) {
+	key, err := Parse("jbsw y3dp-ehpk 3pxp==")
+	if err != nil {
+		t.Fatalf("Failed to parse secret: %v", err)
+	}
+	if key.Secret != "JBSWY3DPEHPK3PXP" || key.Algorithm != "" || key.Digits != 0 || key.Period != 0 {
+		t.Fatalf("Unexpected key: %+v", key)
+	}
+
+	code, err := key.Code(time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("Failed to compute code: %v", err)
+	}
+	if len(code) != DefaultDigits {
+		t.Fatalf("Expected a %d digit code, got %q", DefaultDigits, code)
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	key, err := Parse("otpauth://totp/GitHub:rob?secret=JBSWY3DPEHPK3PXP&issuer=GitHub&algorithm=sha256&digits=8&period=60")
+	if err != nil {
+		t.Fatalf("Failed to parse URI: %v", err)
+	}
+	if key.Secret != "JBSWY3DPEHPK3PXP" || key.Algorithm != "SHA256" || key.Digits != 8 || key.Period != 60 {
+		t.Fatalf("Unexpected key: %+v", key)
+	}
+
+	// The defaults are not stored
+	key, err = Parse("otpauth://totp/rob?secret=JBSWY3DPEHPK3PXP&algorithm=SHA1&digits=6&period=30")
+	if err != nil {
+		t.Fatalf("Failed to parse URI: %v", err)
+	}
+	if *key != (Key{Secret: "JBSWY3DPEHPK3PXP"}) {
+		t.Fatalf("Expected only the secret to be stored, got %+v", key)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, invalid := range []string{
+		"",
+		"not base32!",
+		"otpauth://hotp/rob?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/rob",
+		"otpauth://totp/rob?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/rob?secret=JBSWY3DPEHPK3PXP&digits=six",
+		"otpauth://totp/rob?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://totp/rob?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Fatalf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	key := Key{Secret: "JBSWY3DPEHPK3PXP"}
+	if got := key.Remaining(time.Unix(65, 0)); got != 25*time.Second {
+		t.Fatalf("Expected 25s remaining, got %s", got)
+	}
+	key.Period = 60
+	if got := key.Remaining(time.Unix(60, 500*int64(time.Millisecond))); got != 59500*time.Millisecond {
+		t.Fatalf("Expected 59.5s remaining, got %s", got)
+	}
+}
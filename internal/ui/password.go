@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrNotInteractive is returned when a password has to be typed but stdin is not a terminal.
+var ErrNotInteractive = errors.New("stdin is not a terminal; provide the master password with " +
+	"--password-file, --password-fd, --password-env or --password-command")
+
+// PasswordSource describes where the master password is read from when it is not typed
+// interactively. At most one source may be set.
+type PasswordSource struct {
+	File    string // read the first line of this file
+	FD      int    // read the first line from this file descriptor; negative when unset
+	Env     string // read the value of this environment variable
+	Command string // run this shell command and read the first line of its output
+}
+
+// IsSet reports whether a non-interactive source has been configured.
+func (s PasswordSource) IsSet() bool {
+	return s.File != "" || s.FD >= 0 || s.Env != "" || s.Command != ""
+}
+
+// Validate checks that at most one source is configured.
+func (s PasswordSource) Validate() error {
+	count := 0
+	for _, set := range []bool{s.File != "", s.FD >= 0, s.Env != "", s.Command != ""} {
+		if set {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("only one of --password-file, --password-fd, --password-env and --password-command can be used")
+	}
+	return nil
+}
+
+// Read returns the password from the configured source.
+// Returns an error if the source cannot be read or yields an empty password.
+func (s PasswordSource) Read() (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+
+	var password string
+	var err error
+	switch {
+	case s.File != "":
+		password, err = readPasswordFile(s.File)
+	case s.FD >= 0:
+		password, err = readFirstLine(os.NewFile(uintptr(s.FD), fmt.Sprintf("fd%d", s.FD)))
+	case s.Env != "":
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		password = value
+	case s.Command != "":
+		password, err = runPasswordCommand(s.Command)
+	default:
+		return "", fmt.Errorf("no password source configured")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if password == "" {
+		return "", fmt.Errorf("password source returned an empty password")
+	}
+	return password, nil
+}
+
+// readPasswordFile returns the first line of the file at path.
+func readPasswordFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open password file: %w", err)
+	}
+	defer f.Close()
+	return readFirstLine(f)
+}
+
+// readFirstLine returns the first line read from r, without the line terminator.
+func readFirstLine(r io.Reader) (string, error) {
+	if r == nil {
+		return "", fmt.Errorf("invalid password file descriptor")
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// runPasswordCommand runs command through the system shell and returns the first line
+// of its standard output. Standard error is passed through so prompts and errors from
+// tools such as gpg stay visible.
+func runPasswordCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+	return readFirstLine(bytes.NewReader(output))
+}
+
+// IsInteractive reports whether stdin is a terminal the user can type into.
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ReadMasterPassword returns the master password from source if one is configured.
+// Otherwise it prompts for it with label, or returns ErrNotInteractive if stdin is not a terminal.
+func ReadMasterPassword(label string, source PasswordSource) (string, error) {
+	if source.IsSet() {
+		return source.Read()
+	}
+	if !IsInteractive() {
+		return "", ErrNotInteractive
+	}
+	return PromptPassword(label)
+}
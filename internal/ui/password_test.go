@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPasswordSourceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("s3cret\nsecond line\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+
+	password, err := PasswordSource{File: path, FD: -1}.Read()
+	if err != nil {
+		t.Fatalf("Failed to read password file: %v", err)
+	}
+	if password != "s3cret" {
+		t.Fatalf("Expected 's3cret', got '%s'", password)
+	}
+
+	if _, err := (PasswordSource{File: filepath.Join(t.TempDir(), "missing"), FD: -1}).Read(); err == nil {
+		t.Fatal("Missing password file should fail")
+	}
+}
+
+func TestPasswordSourceFD(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("from-fd\r\n"); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	w.Close()
+
+	password, err := PasswordSource{FD: int(r.Fd())}.Read()
+	if err != nil {
+		t.Fatalf("Failed to read password from fd: %v", err)
+	}
+	if password != "from-fd" {
+		t.Fatalf("Expected 'from-fd', got '%s'", password)
+	}
+}
+
+func TestPasswordSourceEnv(t *testing.T) {
+	t.Setenv("MPASS_TEST_PASSWORD", "from-env")
+
+	password, err := PasswordSource{Env: "MPASS_TEST_PASSWORD", FD: -1}.Read()
+	if err != nil {
+		t.Fatalf("Failed to read password from environment: %v", err)
+	}
+	if password != "from-env" {
+		t.Fatalf("Expected 'from-env', got '%s'", password)
+	}
+
+	if _, err := (PasswordSource{Env: "MPASS_TEST_UNSET_VARIABLE", FD: -1}).Read(); err == nil {
+		t.Fatal("Unset environment variable should fail")
+	}
+}
+
+func TestPasswordSourceCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	password, err := PasswordSource{Command: "printf 'from-command\\nmetadata\\n'", FD: -1}.Read()
+	if err != nil {
+		t.Fatalf("Failed to read password from command: %v", err)
+	}
+	if password != "from-command" {
+		t.Fatalf("Expected 'from-command', got '%s'", password)
+	}
+
+	if _, err := (PasswordSource{Command: "exit 3", FD: -1}).Read(); err == nil {
+		t.Fatal("Failing command should fail")
+	}
+	if _, err := (PasswordSource{Command: "true", FD: -1}).Read(); err == nil {
+		t.Fatal("Empty command output should fail")
+	}
+}
+
+func TestPasswordSourceValidate(t *testing.T) {
+	if (PasswordSource{FD: -1}).IsSet() {
+		t.Fatal("Empty source should not be set")
+	}
+
+	source := PasswordSource{File: "password", Env: "MPASS_PASSWORD", FD: -1}
+	if err := source.Validate(); err == nil {
+		t.Fatal("Multiple sources should be rejected")
+	}
+	if _, err := source.Read(); err == nil {
+		t.Fatal("Reading from multiple sources should fail")
+	}
+}
+
+func TestReadMasterPasswordNotInteractive(t *testing.T) {
+	if IsInteractive() {
+		t.Skip("stdin is a terminal")
+	}
+
+	if _, err := ReadMasterPassword("Enter master password:", PasswordSource{FD: -1}); err != ErrNotInteractive {
+		t.Fatalf("Expected ErrNotInteractive, got %v", err)
+	}
+}
@@ -2,9 +2,11 @@ package ui
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"github.com/manifoldco/promptui"
 	"golang.org/x/term"
+	"io"
 	"mpass/internal/models"
 	"os"
 	"strings"
@@ -36,14 +38,43 @@ func PromptPassword(label string) (string, error) {
 	return string(password), nil
 }
 
+// PromptMultiline prompts the user for text spanning several lines with the given label and
+// reads until end of input (Ctrl-D on a terminal). With concealed the input is not echoed,
+// which requires a terminal. The text is returned as typed, each line ending with a newline.
+func PromptMultiline(label string, concealed bool) (string, error) {
+	fmt.Println(label + " (finish with Ctrl-D on an empty line)")
+	if !concealed {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	var text strings.Builder
+	for {
+		line, err := term.ReadPassword(int(syscall.Stdin))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		text.Write(line)
+		text.WriteByte('\n')
+	}
+	fmt.Println()
+	return text.String(), nil
+}
+
 // SelectEntry displays a list of PasswordEntry items and allows the user to select one.
 // It returns a pointer to the selected PasswordEntry or an error if the selection fails.
 func SelectEntry(entries []models.PasswordEntry) (*models.PasswordEntry, error) {
 	templates := &promptui.SelectTemplates{
 		Label:    "{{ . }}",
-		Active:   "▶ {{ .Username }}@{{ .URL }}",
-		Inactive: "  {{ .Username }}@{{ .URL }}",
-		Selected: "✅ Selected: {{ .Username }}@{{ .URL }}",
+		Active:   "▶ {{ .Title }}",
+		Inactive: "  {{ .Title }}",
+		Selected: "✅ Selected: {{ .Title }}",
 	}
 
 	prompt := promptui.Select{
@@ -59,3 +90,14 @@ func SelectEntry(entries []models.PasswordEntry) (*models.PasswordEntry, error)
 
 	return &entries[index], nil
 }
+
+// PromptConfirm asks the user a yes/no question with the given label.
+// Only "y" or "yes" (case-insensitive) count as confirmation; anything else is a no.
+func PromptConfirm(label string) (bool, error) {
+	answer, err := PromptInput(label + " [y/N]:")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
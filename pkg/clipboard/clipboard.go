@@ -1,12 +1,63 @@
 package clipboard
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os/exec"
-	"runtime"
+	"sort"
 	"strings"
 )
 
+// Backend copies text to a clipboard and reads it back.
+type Backend interface {
+	// Name identifies the backend, as accepted by New.
+	Name() string
+	// Write replaces the clipboard contents with text.
+	Write(text string) error
+	// Read returns the clipboard contents, or ErrReadUnsupported if the backend cannot read.
+	Read() (string, error)
+	// CanRead reports whether Read is supported, without reading the clipboard.
+	CanRead() bool
+}
+
+var (
+	// ErrReadUnsupported is returned by Read on backends that can only write, such as OSC 52.
+	ErrReadUnsupported = errors.New("clipboard backend cannot read the clipboard")
+	// ErrNoBackend is returned by Detect when no usable clipboard backend is found.
+	ErrNoBackend = errors.New("no clipboard backend available; install wl-clipboard, xclip or xsel, " +
+		"or choose one with --clipboard-backend")
+)
+
+// Auto is the backend name that selects the backend with Detect.
+const Auto = "auto"
+
+// New returns the backend with the given name, or the detected one for "" and Auto.
+// Returns an error listing the known backends if the name is unknown.
+func New(name string) (Backend, error) {
+	if name == "" || name == Auto {
+		return Detect()
+	}
+	if name == osc52Name {
+		return newOSC52(), nil
+	}
+	if backend, ok := commandBackends[name]; ok {
+		return backend, nil
+	}
+	return nil, fmt.Errorf("unknown clipboard backend %q (available: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all backends, sorted.
+func Names() []string {
+	names := []string{osc52Name}
+	for name := range commandBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // tryCommand executes an external command with the specified arguments and input.
 // name: name of the command to execute.
 // args: arguments for the command.
@@ -18,25 +69,52 @@ func tryCommand(name string, args []string, input string) error {
 	return cmd.Run()
 }
 
-// WriteText copies the provided text to the system clipboard.
-// It supports macOS, Linux, and Windows by using the appropriate clipboard utility for each OS.
-// Returns an error if the operation fails or the OS is unsupported.
+// readCommand executes an external command and returns its standard output.
+func readCommand(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	return string(out), err
+}
+
+// WriteText copies the provided text to the system clipboard using the detected backend.
+// Returns an error if no backend is available or the operation fails.
 func WriteText(text string) error {
-	switch runtime.GOOS {
-	case "darwin":
-		cmd := exec.Command("pbcopy")
-		cmd.Stdin = strings.NewReader(text)
-		return cmd.Run()
-	case "linux":
-		if err := tryCommand("xclip", []string{"-selection", "clipboard"}, text); err == nil {
-			return nil
-		}
-		return tryCommand("xsel", []string{"--clipboard", "--input"}, text)
-	case "windows":
-		cmd := exec.Command("clip")
-		cmd.Stdin = strings.NewReader(text)
-		return cmd.Run()
-	default:
-		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	backend, err := Detect()
+	if err != nil {
+		return err
+	}
+	return backend.Write(text)
+}
+
+// ReadText returns the contents of the system clipboard using the detected backend.
+// Returns ErrReadUnsupported if the backend can only write.
+func ReadText() (string, error) {
+	backend, err := Detect()
+	if err != nil {
+		return "", err
+	}
+	return backend.Read()
+}
+
+// Digest returns a hex encoded SHA-256 hash of text. It lets a process check whether the
+// clipboard still holds a value without keeping the value itself.
+func Digest(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClearIfUnchanged empties the clipboard of backend if its contents still match digest, as
+// returned by Digest for the copied value. Anything copied since then is left alone, and so
+// is a clipboard that cannot be read. Reports whether the clipboard was cleared.
+func ClearIfUnchanged(backend Backend, digest string) (bool, error) {
+	current, err := backend.Read()
+	if err != nil {
+		return false, fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	if Digest(current) != digest {
+		return false, nil
+	}
+	if err := backend.Write(""); err != nil {
+		return false, fmt.Errorf("failed to clear clipboard: %w", err)
 	}
+	return true, nil
 }
@@ -1,8 +1,9 @@
 package clipboard
 
 import (
-	"os/exec"
+	"errors"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -59,23 +60,10 @@ func TestWriteSpecialCharacters(t *testing.T) {
 	}
 }
 
-// isClipboardAvailable checks if the required clipboard tools are available
+// isClipboardAvailable checks if a clipboard backend that can be read back is available
 func isClipboardAvailable() bool {
-	switch runtime.GOOS {
-	case "darwin":
-		_, err := exec.LookPath("pbcopy")
-		return err == nil
-	case "linux":
-		// Check for either xclip or xsel
-		_, err1 := exec.LookPath("xclip")
-		_, err2 := exec.LookPath("xsel")
-		return err1 == nil || err2 == nil
-	case "windows":
-		_, err := exec.LookPath("clip")
-		return err == nil
-	default:
-		return false
-	}
+	backend, err := Detect()
+	return err == nil && backend.Name() != osc52Name
 }
 
 func TestTryCommand(t *testing.T) {
@@ -104,3 +92,106 @@ func TestTryCommandNonexistent(t *testing.T) {
 		t.Fatal("tryCommand should fail with nonexistent command")
 	}
 }
+
+func TestDigest(t *testing.T) {
+	if Digest("secret") != Digest("secret") {
+		t.Fatal("Digest should be deterministic")
+	}
+	if Digest("secret") == Digest("secret ") {
+		t.Fatal("Different values should have different digests")
+	}
+	if len(Digest("")) != 64 {
+		t.Fatalf("Expected a hex encoded SHA-256 digest, got %q", Digest(""))
+	}
+}
+
+func TestReadText(t *testing.T) {
+	if !isClipboardAvailable() {
+		t.Skip("Clipboard tools not available, skipping test")
+	}
+
+	if err := WriteText("read-back-value"); err != nil {
+		t.Fatalf("Failed to write to clipboard: %v", err)
+	}
+	text, err := ReadText()
+	if err != nil {
+		t.Fatalf("Failed to read clipboard: %v", err)
+	}
+	if text != "read-back-value" {
+		t.Fatalf("Expected %q, got %q", "read-back-value", text)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	backend := NewMemory()
+	if text, err := backend.Read(); err != nil || text != "" {
+		t.Fatalf("Expected an empty clipboard, got %q, %v", text, err)
+	}
+	if err := backend.Write("line1\nline2"); err != nil {
+		t.Fatalf("Failed to write to clipboard: %v", err)
+	}
+	if text, _ := backend.Read(); text != "line1\nline2" {
+		t.Fatalf("Expected the written text, got %q", text)
+	}
+}
+
+func TestClearIfUnchanged(t *testing.T) {
+	backend := NewMemory()
+	if err := backend.Write("copied-secret"); err != nil {
+		t.Fatalf("Failed to write to clipboard: %v", err)
+	}
+	cleared, err := ClearIfUnchanged(backend, Digest("something else"))
+	if err != nil {
+		t.Fatalf("Failed to check clipboard: %v", err)
+	}
+	if cleared {
+		t.Fatal("Clipboard holding another value should not be cleared")
+	}
+
+	cleared, err = ClearIfUnchanged(backend, Digest("copied-secret"))
+	if err != nil {
+		t.Fatalf("Failed to clear clipboard: %v", err)
+	}
+	if !cleared {
+		t.Fatal("Expected the clipboard to be cleared")
+	}
+	if text, _ := backend.Read(); text != "" {
+		t.Fatalf("Expected an empty clipboard, got %q", text)
+	}
+}
+
+func TestClearIfUnchangedUnreadable(t *testing.T) {
+	_, err := ClearIfUnchanged(newOSC52(), Digest("copied-secret"))
+	if !errors.Is(err, ErrReadUnsupported) {
+		t.Fatalf("Expected ErrReadUnsupported, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range Names() {
+		backend, err := New(name)
+		if err != nil {
+			t.Fatalf("Failed to create backend %q: %v", name, err)
+		}
+		if backend.Name() != name {
+			t.Fatalf("Expected backend %q, got %q", name, backend.Name())
+		}
+	}
+
+	_, err := New("nonexistent")
+	if err == nil || !strings.Contains(err.Error(), "wl-copy") {
+		t.Fatalf("Expected an error listing the available backends, got %v", err)
+	}
+}
+
+func TestOSC52ReadUnsupported(t *testing.T) {
+	if _, err := newOSC52().Read(); !errors.Is(err, ErrReadUnsupported) {
+		t.Fatalf("Expected ErrReadUnsupported, got %v", err)
+	}
+	if newOSC52().CanRead() {
+		t.Fatal("OSC 52 should not report read support")
+	}
+	if !NewMemory().CanRead() || !(commandBackend{read: []string{"wl-paste"}}).CanRead() {
+		t.Fatal("Backends with a read command should report read support")
+	}
+}
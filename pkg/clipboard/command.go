@@ -0,0 +1,96 @@
+package clipboard
+
+import "strings"
+
+// Displays whose clipboard mpass can own itself, see Serve.
+const (
+	displayX11     = "x11"
+	displayWayland = "wayland"
+)
+
+// commandBackend uses external programs to write and read the clipboard.
+type commandBackend struct {
+	name  string
+	write []string // command that reads the new contents from stdin
+	read  []string // command that prints the contents; nil if reading is unsupported
+	// trim is removed from the end of what read prints, for tools that append a newline.
+	trim string
+	// display is the display whose clipboard the backend writes, if Serve can own it instead.
+	display string
+}
+
+// commandBackends holds the backends built on clipboard utilities, by name.
+var commandBackends = map[string]commandBackend{
+	"pbcopy": {
+		name:  "pbcopy",
+		write: []string{"pbcopy"},
+		read:  []string{"pbpaste"},
+	},
+	"xclip": {
+		name:    "xclip",
+		write:   []string{"xclip", "-selection", "clipboard"},
+		read:    []string{"xclip", "-selection", "clipboard", "-o"},
+		display: displayX11,
+	},
+	"xsel": {
+		name:    "xsel",
+		write:   []string{"xsel", "--clipboard", "--input"},
+		read:    []string{"xsel", "--clipboard", "--output"},
+		display: displayX11,
+	},
+	"wl-copy": {
+		name:    "wl-copy",
+		write:   []string{"wl-copy"},
+		read:    []string{"wl-paste", "--no-newline"},
+		display: displayWayland,
+	},
+	// -w also forwards the buffer to the outer terminal's clipboard (tmux 3.2+ with set-clipboard).
+	"tmux": {
+		name:  "tmux",
+		write: []string{"tmux", "load-buffer", "-w", "-"},
+		read:  []string{"tmux", "save-buffer", "-"},
+	},
+	"termux": {
+		name:  "termux",
+		write: []string{"termux-clipboard-set"},
+		read:  []string{"termux-clipboard-get"},
+	},
+	"clip": {
+		name:  "clip",
+		write: []string{"clip"},
+		read:  []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+		trim:  "\r\n",
+	},
+}
+
+// Name returns the name of the backend.
+func (b commandBackend) Name() string {
+	return b.name
+}
+
+// Write pipes text into the backend's write command. On X11 and Wayland the text is served
+// through StartServer instead when possible, so that it is marked as sensitive; if the server
+// cannot take the clipboard the command is used after all.
+func (b commandBackend) Write(text string) error {
+	if canServe(b.display, text) && StartServer(b.name, text) == nil {
+		return nil
+	}
+	return tryCommand(b.write[0], b.write[1:], text)
+}
+
+// CanRead reports whether the backend has a read command.
+func (b commandBackend) CanRead() bool {
+	return b.read != nil
+}
+
+// Read returns the output of the backend's read command.
+func (b commandBackend) Read() (string, error) {
+	if b.read == nil {
+		return "", ErrReadUnsupported
+	}
+	text, err := readCommand(b.read[0], b.read[1:]...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(text, b.trim), nil
+}
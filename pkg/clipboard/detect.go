@@ -0,0 +1,92 @@
+package clipboard
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// environment is what Detect looks at, kept separate so detection can be tested.
+type environment struct {
+	goos       string
+	getenv     func(string) string
+	hasCommand func(string) bool
+	hasTTY     func() bool
+}
+
+// Detect returns the clipboard backend for the current session:
+//
+//   - termux-clipboard-set on Termux
+//   - over SSH (SSH_TTY), tmux inside tmux and OSC 52 otherwise, since the local
+//     clipboard tools would only reach the remote machine's clipboard
+//   - pbcopy on macOS and clip on Windows
+//   - wl-copy under Wayland (WAYLAND_DISPLAY), then xclip or xsel under X11 (DISPLAY)
+//   - tmux inside tmux (TMUX), then OSC 52 when there is a terminal
+//
+// Returns ErrNoBackend if none of them can be used.
+func Detect() (Backend, error) {
+	name, err := detect(environment{
+		goos:   runtime.GOOS,
+		getenv: os.Getenv,
+		hasCommand: func(name string) bool {
+			_, err := exec.LookPath(name)
+			return err == nil
+		},
+		hasTTY: func() bool {
+			tty, err := openTTY()
+			if err != nil {
+				return false
+			}
+			tty.Close()
+			return true
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return New(name)
+}
+
+// detect returns the name of the backend to use in env.
+func detect(env environment) (string, error) {
+	inTmux := env.getenv("TMUX") != "" && env.hasCommand("tmux")
+
+	if env.getenv("TERMUX_VERSION") != "" && env.hasCommand("termux-clipboard-set") {
+		return "termux", nil
+	}
+	if env.getenv("SSH_TTY") != "" {
+		if inTmux {
+			return "tmux", nil
+		}
+		return osc52Name, nil
+	}
+
+	switch env.goos {
+	case "darwin":
+		if env.hasCommand("pbcopy") {
+			return "pbcopy", nil
+		}
+	case "windows":
+		if env.hasCommand("clip") {
+			return "clip", nil
+		}
+	}
+
+	if env.getenv("WAYLAND_DISPLAY") != "" && env.hasCommand("wl-copy") {
+		return "wl-copy", nil
+	}
+	if env.getenv("DISPLAY") != "" {
+		for _, name := range []string{"xclip", "xsel"} {
+			if env.hasCommand(name) {
+				return name, nil
+			}
+		}
+	}
+	if inTmux {
+		return "tmux", nil
+	}
+	if env.hasTTY() {
+		return osc52Name, nil
+	}
+	return "", ErrNoBackend
+}
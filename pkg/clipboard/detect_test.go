@@ -0,0 +1,64 @@
+package clipboard
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeEnvironment returns an environment with the given variables and commands.
+func fakeEnvironment(goos string, vars map[string]string, commands []string, tty bool) environment {
+	return environment{
+		goos:   goos,
+		getenv: func(key string) string { return vars[key] },
+		hasCommand: func(name string) bool {
+			for _, command := range commands {
+				if command == name {
+					return true
+				}
+			}
+			return false
+		},
+		hasTTY: func() bool { return tty },
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		vars     map[string]string
+		commands []string
+		tty      bool
+		want     string
+	}{
+		{"macOS", "darwin", nil, []string{"pbcopy"}, true, "pbcopy"},
+		{"Windows", "windows", nil, []string{"clip"}, true, "clip"},
+		{"Wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "xclip"}, true, "wl-copy"},
+		{"Wayland without wl-copy", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"xclip"}, true, "xclip"},
+		{"X11 with xsel", "linux", map[string]string{"DISPLAY": ":0"}, []string{"xsel"}, true, "xsel"},
+		{"SSH", "linux", map[string]string{"SSH_TTY": "/dev/pts/0", "DISPLAY": ":0"}, []string{"xclip"}, true, osc52Name},
+		{"SSH in tmux", "linux", map[string]string{"SSH_TTY": "/dev/pts/0", "TMUX": "/tmp/tmux"}, []string{"tmux"}, true, "tmux"},
+		{"tmux without display", "linux", map[string]string{"TMUX": "/tmp/tmux"}, []string{"tmux"}, true, "tmux"},
+		{"Termux", "android", map[string]string{"TERMUX_VERSION": "0.118"}, []string{"termux-clipboard-set"}, true, "termux"},
+		{"headless terminal", "linux", nil, nil, true, osc52Name},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detect(fakeEnvironment(tt.goos, tt.vars, tt.commands, tt.tty))
+			if err != nil {
+				t.Fatalf("Failed to detect backend: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Expected backend %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectNoBackend(t *testing.T) {
+	_, err := detect(fakeEnvironment("linux", nil, nil, false))
+	if !errors.Is(err, ErrNoBackend) {
+		t.Fatalf("Expected ErrNoBackend, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package clipboard
+
+import "sync"
+
+const memoryName = "memory"
+
+// Memory is a clipboard backend that keeps the contents in memory. It lets tests, and
+// programs embedding the package, copy and read back text without any clipboard tool.
+// It is safe for concurrent use.
+type Memory struct {
+	mu   sync.Mutex
+	text string
+}
+
+// NewMemory returns an empty in-memory clipboard.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Name returns the name of the backend.
+func (m *Memory) Name() string {
+	return memoryName
+}
+
+// Write replaces the stored text.
+func (m *Memory) Write(text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text = text
+	return nil
+}
+
+// CanRead always reports true.
+func (m *Memory) CanRead() bool {
+	return true
+}
+
+// Read returns the stored text.
+func (m *Memory) Read() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.text, nil
+}
@@ -0,0 +1,74 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+const osc52Name = "osc52"
+
+// osc52Backend asks the terminal emulator to set the clipboard with an OSC 52 escape sequence.
+// It works over SSH and in containers without any clipboard tool, as long as the terminal
+// supports it, but the clipboard cannot be read back.
+type osc52Backend struct {
+	// tmux wraps the sequence in a tmux passthrough so it reaches the outer terminal.
+	tmux bool
+	// open returns the terminal to write the sequence to.
+	open func() (io.WriteCloser, error)
+}
+
+// newOSC52 returns an OSC 52 backend writing to the controlling terminal.
+func newOSC52() osc52Backend {
+	return osc52Backend{tmux: os.Getenv("TMUX") != "", open: openTTY}
+}
+
+// openTTY opens the controlling terminal for writing, so the sequence reaches the terminal
+// even when stdout is redirected.
+func openTTY() (io.WriteCloser, error) {
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONOUT$"
+	}
+	return os.OpenFile(name, os.O_WRONLY, 0)
+}
+
+// Name returns the name of the backend.
+func (b osc52Backend) Name() string {
+	return osc52Name
+}
+
+// sequence returns the escape sequence that sets the clipboard to text.
+func (b osc52Backend) sequence(text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	if b.tmux {
+		// Inside the passthrough every ESC has to be doubled.
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// Write sends the OSC 52 sequence for text to the terminal. An empty text clears the clipboard.
+func (b osc52Backend) Write(text string) error {
+	tty, err := b.open()
+	if err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	if _, err := io.WriteString(tty, b.sequence(text)); err != nil {
+		tty.Close()
+		return err
+	}
+	return tty.Close()
+}
+
+// CanRead always reports false, see Read.
+func (b osc52Backend) CanRead() bool {
+	return false
+}
+
+// Read is not supported: terminals do not report the clipboard back by default.
+func (b osc52Backend) Read() (string, error) {
+	return "", ErrReadUnsupported
+}
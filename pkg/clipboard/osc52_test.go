@@ -0,0 +1,35 @@
+package clipboard
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// bufferCloser collects what is written to a fake terminal.
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error {
+	return nil
+}
+
+func TestOSC52Write(t *testing.T) {
+	tty := &bufferCloser{}
+	backend := osc52Backend{open: func() (io.WriteCloser, error) { return tty, nil }}
+
+	if err := backend.Write("secret"); err != nil {
+		t.Fatalf("Failed to write OSC 52 sequence: %v", err)
+	}
+	if got, want := tty.String(), "\x1b]52;c;c2VjcmV0\x07"; got != want {
+		t.Fatalf("Expected sequence %q, got %q", want, got)
+	}
+}
+
+func TestOSC52TmuxPassthrough(t *testing.T) {
+	backend := osc52Backend{tmux: true}
+	if got, want := backend.sequence("secret"), "\x1bPtmux;\x1b\x1b]52;c;c2VjcmV0\x07\x1b\\"; got != want {
+		t.Fatalf("Expected sequence %q, got %q", want, got)
+	}
+}
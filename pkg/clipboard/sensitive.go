@@ -0,0 +1,75 @@
+package clipboard
+
+import "fmt"
+
+// Clipboard managers such as KDE Klipper, CopyQ and GNOME extensions skip entries that offer
+// one of these targets, so secrets served with them stay out of clipboard history.
+const (
+	kdePasswordHintTarget = "x-kde-passwordManagerHint"
+	clipboardStateTarget  = "CLIPBOARD_STATE"
+)
+
+// textTargets are the targets (X11) and MIME types (Wayland) under which the text is offered.
+// Only UTF-8 ones are listed: the text is sent as is, and STRING or plain text/plain promise
+// Latin-1 or ASCII that a password need not fit in.
+var textTargets = []string{"text/plain;charset=utf-8", "UTF8_STRING"}
+
+// sensitiveTargets maps the clipboard manager hints to the data offered for them.
+var sensitiveTargets = map[string]string{
+	kdePasswordHintTarget: "secret",
+	clipboardStateTarget:  "sensitive",
+}
+
+// maxServeSize bounds the text passed to a clipboard server. It travels through a pipe to
+// the background process and is sent to X11 clients in a single request.
+const maxServeSize = 32 * 1024
+
+// StartServer, when set, starts a background process that calls Serve with the backend
+// name and text, and returns once that process owns the clipboard or with the error that
+// kept it from taking it. The X11 and Wayland backends use it to own the clipboard themselves
+// and offer the sensitive targets alongside the text. Without it, or when it fails, they leave
+// the copy to their clipboard tool, which offers the text only.
+var StartServer func(backend, text string) error
+
+// Serve owns the clipboard of the display used by the named backend and offers text together
+// with the sensitive targets until another program takes the clipboard over. ready is called
+// once the clipboard is owned; an error returned before that means the text was never on the
+// clipboard. It is meant to run in the background process started through StartServer.
+func Serve(backend, text string, ready func()) error {
+	switch commandBackends[backend].display {
+	case displayX11:
+		return serveX11(text, ready)
+	case displayWayland:
+		return serveWayland(text, ready)
+	default:
+		return fmt.Errorf("clipboard backend %q cannot serve the clipboard", backend)
+	}
+}
+
+// targetData returns the data offered for target, and false if target is not offered.
+func targetData(target, text string) (string, bool) {
+	if data, ok := sensitiveTargets[target]; ok {
+		return data, true
+	}
+	for _, t := range textTargets {
+		if t == target {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// offeredTargets returns all targets offered by a clipboard server.
+func offeredTargets() []string {
+	return append(append([]string{}, textTargets...), kdePasswordHintTarget, clipboardStateTarget)
+}
+
+// canServe reports whether a clipboard server for display is worth starting for text. Whether
+// the display accepts a connection and supports owning the clipboard is left to the server,
+// which fails through StartServer if it does not.
+func canServe(display, text string) bool {
+	if text == "" || len(text) > maxServeSize || StartServer == nil {
+		return false
+	}
+	return display == displayX11 || display == displayWayland
+}
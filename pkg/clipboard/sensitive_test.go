@@ -0,0 +1,56 @@
+package clipboard
+
+import "testing"
+
+func TestTargetData(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"UTF8_STRING", "secret-value", true},
+		{"text/plain;charset=utf-8", "secret-value", true},
+		{"STRING", "", false},
+		{"TEXT", "", false},
+		{kdePasswordHintTarget, "secret", true},
+		{clipboardStateTarget, "sensitive", true},
+		{"image/png", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := targetData(tt.target, "secret-value")
+		if got != tt.want || ok != tt.ok {
+			t.Fatalf("targetData(%q) = %q, %v; expected %q, %v", tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCanServe(t *testing.T) {
+	orig := StartServer
+	defer func() { StartServer = orig }()
+
+	StartServer = nil
+	if canServe(displayX11, "secret") {
+		t.Fatal("Should not serve without StartServer")
+	}
+
+	StartServer = func(string, string) error { return nil }
+	if !canServe(displayX11, "secret") || !canServe(displayWayland, "secret") {
+		t.Fatal("Should leave checking the display to the server")
+	}
+	if canServe(displayX11, "") {
+		t.Fatal("Clearing the clipboard should be left to the clipboard tool")
+	}
+	if canServe("", "secret") {
+		t.Fatal("Backends without a display cannot be served")
+	}
+	big := make([]byte, maxServeSize+1)
+	if canServe(displayWayland, string(big)) {
+		t.Fatal("Text larger than maxServeSize should not be served")
+	}
+}
+
+func TestServeUnknownBackend(t *testing.T) {
+	if err := Serve("pbcopy", "secret", func() { t.Fatal("ready should not be called") }); err == nil {
+		t.Fatal("Serve should fail for backends without a display")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package clipboard
+
+import "errors"
+
+// errNoDisplay is returned on Windows, which has neither X11 nor Wayland.
+var errNoDisplay = errors.New("serving the clipboard requires X11 or Wayland")
+
+func serveX11(string, func()) error {
+	return errNoDisplay
+}
+
+func serveWayland(string, func()) error {
+	return errNoDisplay
+}
@@ -0,0 +1,331 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// This file implements just enough of the Wayland protocol to own the clipboard through the
+// data control extension (ext-data-control-v1 or the older wlr-data-control-unstable-v1),
+// which KDE Plasma and wlroots based compositors support. It lets a client without a window
+// set the selection, which is what clipboard managers use too.
+
+// Object IDs used by the client, in the order they are created.
+const (
+	wlDisplayID = iota + 1
+	wlRegistryID
+	wlCallbackID
+	wlSeatID
+	wlManagerID
+	wlSourceID
+	wlDeviceID
+	wlReadyCallbackID
+)
+
+// Request and event opcodes. The ext and wlr data control interfaces share their layout.
+const (
+	wlDisplaySync        = 0
+	wlDisplayGetRegistry = 1
+	wlDisplayError       = 0
+	wlRegistryBind       = 0
+	wlRegistryGlobal     = 0
+	wlCallbackDone       = 0
+
+	dataControlCreateSource = 0
+	dataControlGetDevice    = 1
+	dataControlSetSelection = 0
+	dataControlSourceOffer  = 0
+	dataControlSourceSend   = 0
+	dataControlCancelled    = 1
+	dataControlFinished     = 2
+)
+
+// dataControlManagers are the supported manager interfaces, preferred first.
+var dataControlManagers = []string{"ext_data_control_manager_v1", "zwlr_data_control_manager_v1"}
+
+// wlGlobal is an object advertised by the compositor's registry.
+type wlGlobal struct {
+	name    uint32
+	version uint32
+}
+
+// wlConn is a connection to a Wayland compositor.
+type wlConn struct {
+	conn *net.UnixConn
+	buf  []byte // received bytes not yet decoded
+	fds  []int  // received file descriptors not yet consumed
+}
+
+// wlRequest is a request to send to the compositor.
+type wlRequest struct {
+	object uint32
+	opcode uint16
+	args   []byte
+}
+
+// wlMessage is a decoded event.
+type wlMessage struct {
+	object uint32
+	opcode uint16
+	args   []byte
+}
+
+// dialWayland connects to the compositor named by $WAYLAND_DISPLAY.
+func dialWayland() (*wlConn, error) {
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		return nil, errors.New("WAYLAND_DISPLAY is not set")
+	}
+	if !filepath.IsAbs(display) {
+		display = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), display)
+	}
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: display, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Wayland compositor: %w", err)
+	}
+	return &wlConn{conn: conn}, nil
+}
+
+// Close closes the connection and any file descriptors that were not consumed.
+func (c *wlConn) Close() error {
+	for _, fd := range c.fds {
+		unix.Close(fd)
+	}
+	return c.conn.Close()
+}
+
+// wlArgs encodes request arguments: uint32 values as they are and strings with their
+// length, terminating NUL and padding.
+func wlArgs(args ...interface{}) []byte {
+	var b []byte
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case uint32:
+			b = binary.NativeEndian.AppendUint32(b, v)
+		case string:
+			b = binary.NativeEndian.AppendUint32(b, uint32(len(v)+1))
+			b = append(b, v...)
+			b = append(b, make([]byte, 1+pad4(len(v)+1))...)
+		}
+	}
+	return b
+}
+
+// wlString decodes a string argument and returns it with the remaining arguments.
+func wlString(args []byte) (string, []byte, error) {
+	if len(args) < 4 {
+		return "", nil, errors.New("truncated Wayland message")
+	}
+	n := int(binary.NativeEndian.Uint32(args))
+	size := 4 + n + pad4(n)
+	if n == 0 || len(args) < size {
+		return "", nil, errors.New("truncated Wayland message")
+	}
+	return string(args[4 : 4+n-1]), args[size:], nil
+}
+
+// send writes a request to object.
+func (c *wlConn) send(object uint32, opcode uint16, args []byte) error {
+	msg := binary.NativeEndian.AppendUint32(nil, object)
+	msg = binary.NativeEndian.AppendUint32(msg, uint32(8+len(args))<<16|uint32(opcode))
+	_, err := c.conn.Write(append(msg, args...))
+	return err
+}
+
+// read returns the next event, collecting any file descriptors sent along with it.
+func (c *wlConn) read() (wlMessage, error) {
+	for {
+		if len(c.buf) >= 8 {
+			size := int(binary.NativeEndian.Uint32(c.buf[4:]) >> 16)
+			if size < 8 {
+				return wlMessage{}, errors.New("invalid Wayland message")
+			}
+			if len(c.buf) >= size {
+				msg := wlMessage{
+					object: binary.NativeEndian.Uint32(c.buf),
+					opcode: uint16(binary.NativeEndian.Uint32(c.buf[4:])),
+					args:   append([]byte(nil), c.buf[8:size]...),
+				}
+				c.buf = c.buf[size:]
+				return msg, nil
+			}
+		}
+
+		data := make([]byte, 4096)
+		oob := make([]byte, unix.CmsgSpace(28*4))
+		n, oobn, _, _, err := c.conn.ReadMsgUnix(data, oob)
+		if err != nil {
+			return wlMessage{}, err
+		}
+		if n == 0 {
+			return wlMessage{}, errors.New("Wayland compositor closed the connection")
+		}
+		c.buf = append(c.buf, data[:n]...)
+		cmsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return wlMessage{}, err
+		}
+		for _, cmsg := range cmsgs {
+			fds, err := unix.ParseUnixRights(&cmsg)
+			if err == nil {
+				c.fds = append(c.fds, fds...)
+			}
+		}
+	}
+}
+
+// checkError returns the error carried by a wl_display.error event, if msg is one.
+func checkError(msg wlMessage) error {
+	if msg.object != wlDisplayID || msg.opcode != wlDisplayError || len(msg.args) < 8 {
+		return nil
+	}
+	message, _, _ := wlString(msg.args[8:])
+	return fmt.Errorf("Wayland protocol error: %s", message)
+}
+
+// globals lists the objects advertised by the compositor, keyed by interface.
+func (c *wlConn) globals() (map[string]wlGlobal, error) {
+	if err := c.send(wlDisplayID, wlDisplayGetRegistry, wlArgs(uint32(wlRegistryID))); err != nil {
+		return nil, err
+	}
+	if err := c.send(wlDisplayID, wlDisplaySync, wlArgs(uint32(wlCallbackID))); err != nil {
+		return nil, err
+	}
+
+	globals := map[string]wlGlobal{}
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkError(msg); err != nil {
+			return nil, err
+		}
+		switch {
+		case msg.object == wlCallbackID && msg.opcode == wlCallbackDone:
+			return globals, nil
+		case msg.object == wlRegistryID && msg.opcode == wlRegistryGlobal && len(msg.args) >= 4:
+			iface, rest, err := wlString(msg.args[4:])
+			if err != nil || len(rest) < 4 {
+				continue
+			}
+			if _, seen := globals[iface]; !seen {
+				globals[iface] = wlGlobal{
+					name:    binary.NativeEndian.Uint32(msg.args),
+					version: binary.NativeEndian.Uint32(rest),
+				}
+			}
+		}
+	}
+}
+
+// dataControlManager returns the interface name and global of the first supported data
+// control manager, and fails if the compositor has none or no seat.
+func dataControlManager(globals map[string]wlGlobal) (string, wlGlobal, error) {
+	if _, ok := globals["wl_seat"]; !ok {
+		return "", wlGlobal{}, errors.New("Wayland compositor has no seat")
+	}
+	for _, iface := range dataControlManagers {
+		if global, ok := globals[iface]; ok {
+			return iface, global, nil
+		}
+	}
+	return "", wlGlobal{}, errors.New("Wayland compositor does not support the data control protocol")
+}
+
+// serveWayland sets the clipboard selection and sends text to clients that paste it until
+// another client sets the selection. ready is called once the compositor has processed the
+// selection request without an error.
+func serveWayland(text string, ready func()) error {
+	c, err := dialWayland()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.serve(text, ready)
+}
+
+// serve does the work of serveWayland on an established connection.
+func (c *wlConn) serve(text string, ready func()) error {
+	globals, err := c.globals()
+	if err != nil {
+		return err
+	}
+	manager, managerGlobal, err := dataControlManager(globals)
+	if err != nil {
+		return err
+	}
+
+	requests := []wlRequest{
+		{wlRegistryID, wlRegistryBind, wlArgs(globals["wl_seat"].name, "wl_seat", uint32(1), uint32(wlSeatID))},
+		{wlRegistryID, wlRegistryBind, wlArgs(managerGlobal.name, manager, uint32(1), uint32(wlManagerID))},
+		{wlManagerID, dataControlCreateSource, wlArgs(uint32(wlSourceID))},
+	}
+	for _, target := range offeredTargets() {
+		requests = append(requests, wlRequest{wlSourceID, dataControlSourceOffer, wlArgs(target)})
+	}
+	requests = append(requests,
+		wlRequest{wlManagerID, dataControlGetDevice, wlArgs(uint32(wlDeviceID), uint32(wlSeatID))},
+		wlRequest{wlDeviceID, dataControlSetSelection, wlArgs(uint32(wlSourceID))},
+		// The compositor answers requests in order, so once this callback is done the
+		// requests above went through.
+		wlRequest{wlDisplayID, wlDisplaySync, wlArgs(uint32(wlReadyCallbackID))},
+	)
+	for _, req := range requests {
+		if err := c.send(req.object, req.opcode, req.args); err != nil {
+			return err
+		}
+	}
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return err
+		}
+		if err := checkError(msg); err != nil {
+			return err
+		}
+		switch {
+		case msg.object == wlReadyCallbackID && msg.opcode == wlCallbackDone:
+			ready()
+		case msg.object == wlSourceID && msg.opcode == dataControlSourceSend:
+			if err := c.sendData(msg, text); err != nil {
+				return err
+			}
+		case msg.object == wlSourceID && msg.opcode == dataControlCancelled:
+			return nil
+		case msg.object == wlDeviceID && msg.opcode == dataControlFinished:
+			return errors.New("Wayland data control device is no longer valid")
+		}
+	}
+}
+
+// sendData writes the data for the MIME type requested by a send event to the file
+// descriptor that came with it.
+func (c *wlConn) sendData(msg wlMessage, text string) error {
+	mime, _, err := wlString(msg.args)
+	if err != nil {
+		return err
+	}
+	if len(c.fds) == 0 {
+		return errors.New("Wayland send event without a file descriptor")
+	}
+	fd := c.fds[0]
+	c.fds = c.fds[1:]
+
+	f := os.NewFile(uintptr(fd), "wayland-send")
+	defer f.Close()
+	if data, ok := targetData(mime, text); ok {
+		// A client that stops reading early must not stop the server.
+		f.WriteString(data)
+	}
+	return nil
+}
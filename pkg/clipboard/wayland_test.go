@@ -0,0 +1,201 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestWlArgs(t *testing.T) {
+	args := wlArgs(uint32(7), "wl_seat", uint32(1))
+	// 4 bytes for 7, 4 for the length, "wl_seat" with its NUL padded to 8, 4 for 1.
+	if len(args) != 20 {
+		t.Fatalf("Expected 20 bytes, got %d", len(args))
+	}
+	if binary.NativeEndian.Uint32(args) != 7 || binary.NativeEndian.Uint32(args[16:]) != 1 {
+		t.Fatalf("Unexpected encoding %v", args)
+	}
+
+	s, rest, err := wlString(args[4:])
+	if err != nil {
+		t.Fatalf("Failed to decode string: %v", err)
+	}
+	if s != "wl_seat" || len(rest) != 4 {
+		t.Fatalf("Expected wl_seat followed by 4 bytes, got %q and %d bytes", s, len(rest))
+	}
+}
+
+func TestWlStringTruncated(t *testing.T) {
+	args := wlArgs("text/plain")
+	if _, _, err := wlString(args[:len(args)-4]); err == nil {
+		t.Fatal("Expected an error for a truncated string")
+	}
+	if _, _, err := wlString(nil); err == nil {
+		t.Fatal("Expected an error for missing arguments")
+	}
+}
+
+func TestDataControlManager(t *testing.T) {
+	globals := map[string]wlGlobal{
+		"wl_seat":                      {name: 1},
+		"zwlr_data_control_manager_v1": {name: 2},
+	}
+	iface, global, err := dataControlManager(globals)
+	if err != nil || iface != "zwlr_data_control_manager_v1" || global.name != 2 {
+		t.Fatalf("Expected the wlr manager, got %q %v %v", iface, global, err)
+	}
+
+	globals["ext_data_control_manager_v1"] = wlGlobal{name: 3}
+	if iface, _, _ := dataControlManager(globals); iface != "ext_data_control_manager_v1" {
+		t.Fatalf("Expected the ext manager to be preferred, got %q", iface)
+	}
+
+	if _, _, err := dataControlManager(map[string]wlGlobal{"wl_seat": {name: 1}}); err == nil {
+		t.Fatal("Expected an error without a data control manager")
+	}
+}
+
+// fakeCompositor is the part of a Wayland compositor that serveWayland talks to: it
+// advertises a seat and a data control manager, answers sync requests and passes the
+// offered MIME types on to the test. With failSelection it answers the sync after
+// set_selection with a protocol error instead.
+type fakeCompositor struct {
+	conn          *wlConn
+	failSelection bool
+	offers        chan string
+	selected      chan struct{}
+}
+
+func newFakeCompositor(t *testing.T, failSelection bool) (*fakeCompositor, *wlConn) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("Failed to create socket pair: %v", err)
+	}
+	conns := make([]*net.UnixConn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "wayland")
+		conn, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Failed to open socket: %v", err)
+		}
+		conns[i] = conn.(*net.UnixConn)
+	}
+	client, server := &wlConn{conn: conns[0]}, &wlConn{conn: conns[1]}
+	t.Cleanup(func() { client.Close(); server.Close() })
+
+	f := &fakeCompositor{conn: server, failSelection: failSelection, offers: make(chan string, 16), selected: make(chan struct{})}
+	go f.run()
+	return f, client
+}
+
+func (f *fakeCompositor) run() {
+	for {
+		msg, err := f.conn.read()
+		if err != nil {
+			return
+		}
+		switch {
+		case msg.object == wlDisplayID && msg.opcode == wlDisplayGetRegistry:
+			f.conn.send(wlRegistryID, wlRegistryGlobal, wlArgs(uint32(1), "wl_seat", uint32(7)))
+			f.conn.send(wlRegistryID, wlRegistryGlobal, wlArgs(uint32(2), "ext_data_control_manager_v1", uint32(1)))
+		case msg.object == wlDisplayID && msg.opcode == wlDisplaySync:
+			callback := binary.NativeEndian.Uint32(msg.args)
+			if callback == wlReadyCallbackID && f.failSelection {
+				f.conn.send(wlDisplayID, wlDisplayError, wlArgs(uint32(wlDeviceID), uint32(0), "selection refused"))
+				continue
+			}
+			f.conn.send(callback, wlCallbackDone, wlArgs(uint32(0)))
+		case msg.object == wlSourceID && msg.opcode == dataControlSourceOffer:
+			mime, _, _ := wlString(msg.args)
+			f.offers <- mime
+		case msg.object == wlDeviceID && msg.opcode == dataControlSetSelection:
+			close(f.selected)
+		}
+	}
+}
+
+// paste asks the source for mime the way a pasting client does and returns what it wrote.
+func (f *fakeCompositor) paste(t *testing.T, mime string) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+
+	args := wlArgs(mime)
+	msg := binary.NativeEndian.AppendUint32(nil, wlSourceID)
+	msg = binary.NativeEndian.AppendUint32(msg, uint32(8+len(args))<<16|dataControlSourceSend)
+	_, _, err = f.conn.conn.WriteMsgUnix(append(msg, args...), unix.UnixRights(int(w.Fd())), nil)
+	w.Close()
+	if err != nil {
+		t.Fatalf("Failed to send the send event: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read the pasted data: %v", err)
+	}
+	return string(data)
+}
+
+func TestServeWayland(t *testing.T) {
+	f, c := newFakeCompositor(t, false)
+	ready := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- c.serve("hunter2", func() { close(ready) }) }()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("Serve failed before owning the clipboard: %v", err)
+	}
+	select {
+	case <-f.selected:
+	default:
+		t.Fatal("Expected the selection to be set before ready")
+	}
+	offered := map[string]bool{}
+	for range offeredTargets() {
+		offered[<-f.offers] = true
+	}
+	if !offered["text/plain;charset=utf-8"] || !offered[kdePasswordHintTarget] {
+		t.Fatalf("Expected the text and the sensitive hints to be offered, got %v", offered)
+	}
+
+	if got := f.paste(t, "text/plain;charset=utf-8"); got != "hunter2" {
+		t.Fatalf("Expected the text to be pasted, got %q", got)
+	}
+	if got := f.paste(t, clipboardStateTarget); got != "sensitive" {
+		t.Fatalf("Expected the clipboard state hint, got %q", got)
+	}
+	if got := f.paste(t, "image/png"); got != "" {
+		t.Fatalf("Expected nothing for a type that is not offered, got %q", got)
+	}
+
+	f.conn.send(wlSourceID, dataControlCancelled, nil)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected serve to end when the source is cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not end after the source was cancelled")
+	}
+}
+
+func TestServeWaylandSelectionRefused(t *testing.T) {
+	_, c := newFakeCompositor(t, true)
+	err := c.serve("hunter2", func() { t.Fatal("ready should not be called after a protocol error") })
+	if err == nil || !strings.Contains(err.Error(), "selection refused") {
+		t.Fatalf("Expected the protocol error, got %v", err)
+	}
+}
@@ -0,0 +1,404 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// This file implements just enough of the X11 protocol to own the CLIPBOARD selection:
+// connect, intern atoms, create a window and answer SelectionRequest events.
+
+// X11 request opcodes.
+const (
+	x11CreateWindow      = 1
+	x11ChangeProperty    = 18
+	x11InternAtom        = 16
+	x11SetSelectionOwner = 22
+	x11GetSelectionOwner = 23
+	x11SendEvent         = 25
+)
+
+// X11 event codes.
+const (
+	x11Error            = 0
+	x11Reply            = 1
+	x11SelectionClear   = 29
+	x11SelectionRequest = 30
+	x11SelectionNotify  = 31
+)
+
+// Predefined X11 atoms.
+const (
+	x11AtomNone = 0
+	x11AtomAtom = 4
+)
+
+// x11Conn is a connection to an X server.
+type x11Conn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	root   uint32
+	nextID uint32
+	maxLen int // maximum request length in bytes
+}
+
+// x11Display splits a DISPLAY value such as ":0", ":1.0" or "host:10.0" into host and
+// display number.
+func x11Display(display string) (host, number string, err error) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	host, number = display[:i], display[i+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot]
+	}
+	if _, err := strconv.Atoi(number); err != nil {
+		return "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	return host, number, nil
+}
+
+// dialX11 connects to the X server named by $DISPLAY.
+func dialX11() (*x11Conn, error) {
+	host, number, err := x11Display(os.Getenv("DISPLAY"))
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if host == "" || host == "unix" {
+		conn, err = net.Dial("unix", "/tmp/.X11-unix/X"+number)
+	} else {
+		port, _ := strconv.Atoi(number)
+		conn, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+port)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+
+	c := &x11Conn{conn: conn, r: bufio.NewReader(conn)}
+	authName, authData := x11Cookie(host, number)
+	if err := c.setup(authName, authData); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// x11Cookie returns the MIT-MAGIC-COOKIE-1 for the display from the Xauthority file, or
+// empty values if there is none, in which case the connection is attempted without one.
+func x11Cookie(host, number string) (string, []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+	if host == "" || host == "unix" {
+		host, _ = os.Hostname()
+	}
+	return findXauthCookie(data, host, number)
+}
+
+// findXauthCookie looks up the MIT-MAGIC-COOKIE-1 for host and display number in the
+// contents of an Xauthority file.
+func findXauthCookie(data []byte, host, number string) (string, []byte) {
+	const (
+		familyLocal = 256
+		familyWild  = 65535
+		cookieName  = "MIT-MAGIC-COOKIE-1"
+	)
+	readField := func() ([]byte, bool) {
+		if len(data) < 2 {
+			return nil, false
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			return nil, false
+		}
+		field := data[2 : 2+n]
+		data = data[2+n:]
+		return field, true
+	}
+
+	for len(data) >= 2 {
+		family := binary.BigEndian.Uint16(data)
+		data = data[2:]
+		address, ok1 := readField()
+		num, ok2 := readField()
+		name, ok3 := readField()
+		cookie, ok4 := readField()
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			break
+		}
+		if string(name) != cookieName || (len(num) > 0 && string(num) != number) {
+			continue
+		}
+		if family == familyWild || (family == familyLocal && string(address) == host) {
+			return cookieName, cookie
+		}
+	}
+	return "", nil
+}
+
+// pad4 returns the number of bytes needed to pad n to a multiple of four.
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
+
+// setup performs the connection handshake and reads the root window of the first screen.
+func (c *x11Conn) setup(authName string, authData []byte) error {
+	var req bytes.Buffer
+	req.WriteByte('l') // little endian
+	req.WriteByte(0)
+	binary.Write(&req, binary.LittleEndian, []uint16{11, 0, uint16(len(authName)), uint16(len(authData)), 0})
+	req.WriteString(authName)
+	req.Write(make([]byte, pad4(len(authName))))
+	req.Write(authData)
+	req.Write(make([]byte, pad4(len(authData))))
+	if _, err := c.conn.Write(req.Bytes()); err != nil {
+		return fmt.Errorf("failed to connect to X server: %w", err)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	body := make([]byte, 4*int(binary.LittleEndian.Uint16(header[6:])))
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	if header[0] != 1 {
+		reason := body
+		if header[0] == 0 && int(header[1]) <= len(body) {
+			reason = body[:header[1]]
+		}
+		return fmt.Errorf("X server refused the connection: %s", strings.TrimSpace(string(reason)))
+	}
+	if len(body) < 32 {
+		return errors.New("invalid X server setup reply")
+	}
+
+	c.nextID = binary.LittleEndian.Uint32(body[4:])
+	c.maxLen = 4 * int(binary.LittleEndian.Uint16(body[18:]))
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	numFormats := int(body[21])
+	screen := 32 + vendorLen + pad4(vendorLen) + 8*numFormats
+	if len(body) < screen+4 {
+		return errors.New("invalid X server setup reply")
+	}
+	c.root = binary.LittleEndian.Uint32(body[screen:])
+	return nil
+}
+
+// send writes a request with the given opcode, data byte and body, which must be padded.
+func (c *x11Conn) send(opcode, data byte, body []byte) error {
+	req := make([]byte, 4, 4+len(body))
+	req[0], req[1] = opcode, data
+	binary.LittleEndian.PutUint16(req[2:], uint16((4+len(body))/4))
+	_, err := c.conn.Write(append(req, body...))
+	return err
+}
+
+// readReply reads the reply to the last request, which must be the only one outstanding.
+func (c *x11Conn) readReply() ([]byte, error) {
+	reply := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(c.r, reply); err != nil {
+			return nil, err
+		}
+		switch reply[0] {
+		case x11Error:
+			return nil, fmt.Errorf("X request failed with error %d", reply[1])
+		case x11Reply:
+			extra := make([]byte, 4*int(binary.LittleEndian.Uint32(reply[4:])))
+			if _, err := io.ReadFull(c.r, extra); err != nil {
+				return nil, err
+			}
+			return append(reply, extra...), nil
+		}
+		// Events that arrive before the reply are not needed yet.
+	}
+}
+
+// u32s encodes values as consecutive little endian uint32s.
+func u32s(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// internAtom returns the atom for name, creating it if needed.
+func (c *x11Conn) internAtom(name string) (uint32, error) {
+	body := make([]byte, 4, 4+len(name)+pad4(len(name)))
+	binary.LittleEndian.PutUint16(body, uint16(len(name)))
+	body = append(body, name...)
+	body = append(body, make([]byte, pad4(len(name)))...)
+	if err := c.send(x11InternAtom, 0, body); err != nil {
+		return 0, err
+	}
+	reply, err := c.readReply()
+	if err != nil {
+		return 0, fmt.Errorf("failed to intern atom %s: %w", name, err)
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// createWindow creates the invisible window that owns the selection.
+func (c *x11Conn) createWindow() (uint32, error) {
+	const inputOnly = 2
+	window := c.nextID
+	body := append(u32s(window, c.root), make([]byte, 14)...)
+	binary.LittleEndian.PutUint16(body[8+4:], 1) // width
+	binary.LittleEndian.PutUint16(body[8+6:], 1) // height
+	binary.LittleEndian.PutUint16(body[8+10:], inputOnly)
+	body = append(body[:20], u32s(0, 0)...) // visual: CopyFromParent, no attributes
+	return window, c.send(x11CreateWindow, 0, body)
+}
+
+// selectionOwner returns the window owning selection.
+func (c *x11Conn) selectionOwner(selection uint32) (uint32, error) {
+	if err := c.send(x11GetSelectionOwner, 0, u32s(selection)); err != nil {
+		return 0, err
+	}
+	reply, err := c.readReply()
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// changeProperty replaces property on window with data of the given type and format.
+func (c *x11Conn) changeProperty(window, property, typ uint32, format byte, data []byte) error {
+	body := u32s(window, property, typ, uint32(format), uint32(len(data)*8/int(format)))
+	body = append(body, data...)
+	body = append(body, make([]byte, pad4(len(data)))...)
+	return c.send(x11ChangeProperty, 0, body)
+}
+
+// serveX11 owns the CLIPBOARD selection and answers requests for text until another client
+// takes the selection over. ready is called once the selection is owned.
+func serveX11(text string, ready func()) error {
+	c, err := dialX11()
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+	return c.serve(text, ready)
+}
+
+// serve does the work of serveX11 on an established connection.
+func (c *x11Conn) serve(text string, ready func()) error {
+	if len(text) > c.maxLen-64 {
+		return errors.New("text is too large for a single X request")
+	}
+
+	atoms := map[string]uint32{}
+	for _, name := range append([]string{"CLIPBOARD", "TARGETS"}, offeredTargets()...) {
+		atom, err := c.internAtom(name)
+		if err != nil {
+			return err
+		}
+		atoms[name] = atom
+	}
+	names := map[uint32]string{}
+	for name, atom := range atoms {
+		names[atom] = name
+	}
+
+	window, err := c.createWindow()
+	if err != nil {
+		return fmt.Errorf("failed to create window: %w", err)
+	}
+	clipboardAtom := atoms["CLIPBOARD"]
+	if err := c.send(x11SetSelectionOwner, 0, u32s(window, clipboardAtom, 0)); err != nil {
+		return err
+	}
+	owner, err := c.selectionOwner(clipboardAtom)
+	if err != nil {
+		return fmt.Errorf("failed to take the clipboard: %w", err)
+	}
+	if owner != window {
+		return errors.New("another client kept the clipboard")
+	}
+	ready()
+
+	var targetList []uint32
+	for _, name := range append([]string{"TARGETS"}, offeredTargets()...) {
+		targetList = append(targetList, atoms[name])
+	}
+
+	event := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(c.r, event); err != nil {
+			return err
+		}
+		switch event[0] & 0x7f {
+		case x11SelectionClear:
+			return nil
+		case x11Reply:
+			// Replies carry extra data that must be skipped; none are expected here.
+			extra := make([]byte, 4*int(binary.LittleEndian.Uint32(event[4:])))
+			if _, err := io.ReadFull(c.r, extra); err != nil {
+				return err
+			}
+		case x11SelectionRequest:
+			if err := c.answer(event, names, targetList, text); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// answer converts the selection for a SelectionRequest event and notifies the requestor.
+func (c *x11Conn) answer(event []byte, names map[uint32]string, targets []uint32, text string) error {
+	time := binary.LittleEndian.Uint32(event[4:])
+	requestor := binary.LittleEndian.Uint32(event[12:])
+	selection := binary.LittleEndian.Uint32(event[16:])
+	target := binary.LittleEndian.Uint32(event[20:])
+	property := binary.LittleEndian.Uint32(event[24:])
+	if property == x11AtomNone {
+		property = target // obsolete clients
+	}
+
+	var err error
+	switch name := names[target]; {
+	case name == "TARGETS":
+		err = c.changeProperty(requestor, property, x11AtomAtom, 32, u32s(targets...))
+	default:
+		data, ok := targetData(name, text)
+		if !ok {
+			property = x11AtomNone
+			break
+		}
+		err = c.changeProperty(requestor, property, target, 8, []byte(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	notify := make([]byte, 32)
+	notify[0] = x11SelectionNotify
+	copy(notify[4:], u32s(time, requestor, selection, target, property))
+	return c.send(x11SendEvent, 0, append(u32s(requestor, 0), notify...))
+}
@@ -0,0 +1,233 @@
+//go:build !windows
+
+package clipboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestX11Display(t *testing.T) {
+	tests := []struct {
+		display, host, number string
+	}{
+		{":0", "", "0"},
+		{":1.0", "", "1"},
+		{"localhost:10.0", "localhost", "10"},
+		{"unix:2", "unix", "2"},
+	}
+	for _, tt := range tests {
+		host, number, err := x11Display(tt.display)
+		if err != nil || host != tt.host || number != tt.number {
+			t.Fatalf("x11Display(%q) = %q, %q, %v", tt.display, host, number, err)
+		}
+	}
+
+	for _, invalid := range []string{"", "0", ":x"} {
+		if _, _, err := x11Display(invalid); err == nil {
+			t.Fatalf("x11Display(%q) should fail", invalid)
+		}
+	}
+}
+
+// xauthEntry encodes an entry of an Xauthority file.
+func xauthEntry(family uint16, fields ...string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, family)
+	for _, field := range fields {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(field)))
+		b = append(b, field...)
+	}
+	return b
+}
+
+func TestFindXauthCookie(t *testing.T) {
+	var data []byte
+	data = append(data, xauthEntry(256, "otherhost", "0", "MIT-MAGIC-COOKIE-1", "other")...)
+	data = append(data, xauthEntry(256, "myhost", "1", "MIT-MAGIC-COOKIE-1", "display1")...)
+	data = append(data, xauthEntry(256, "myhost", "0", "MIT-MAGIC-COOKIE-1", "display0")...)
+
+	name, cookie := findXauthCookie(data, "myhost", "0")
+	if name != "MIT-MAGIC-COOKIE-1" || string(cookie) != "display0" {
+		t.Fatalf("Expected the cookie of display 0, got %q %q", name, cookie)
+	}
+
+	if name, _ := findXauthCookie(data, "myhost", "5"); name != "" {
+		t.Fatal("Expected no cookie for an unknown display")
+	}
+
+	wild := xauthEntry(65535, "", "", "MIT-MAGIC-COOKIE-1", "wild")
+	if _, cookie := findXauthCookie(wild, "anyhost", "3"); string(cookie) != "wild" {
+		t.Fatalf("Expected the wildcard cookie, got %q", cookie)
+	}
+
+	if name, _ := findXauthCookie(data[:len(data)-3], "myhost", "0"); name != "" {
+		t.Fatal("A truncated entry should be ignored")
+	}
+}
+
+func TestU32s(t *testing.T) {
+	b := u32s(1, 0x01020304)
+	if len(b) != 8 || b[0] != 1 || b[4] != 4 || b[7] != 1 {
+		t.Fatalf("Unexpected encoding %v", b)
+	}
+}
+
+// fakeX11 is the part of an X server that serveX11 talks to after the connection setup. It
+// answers InternAtom and GetSelectionOwner and passes ChangeProperty and SendEvent requests
+// on to the test.
+type fakeX11 struct {
+	conn     net.Conn
+	owner    uint32 // when not zero, the owner reported whatever SetSelectionOwner asked for
+	mu       sync.Mutex
+	atoms    map[string]uint32
+	requests chan []byte
+}
+
+func newFakeX11(t *testing.T, owner uint32) (*fakeX11, *x11Conn) {
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close(); server.Close() })
+	f := &fakeX11{conn: server, owner: owner, atoms: map[string]uint32{}, requests: make(chan []byte, 16)}
+	go f.run()
+	return f, &x11Conn{conn: client, r: bufio.NewReader(client), root: 1, nextID: 0x200000, maxLen: 4 * 0xffff}
+}
+
+func (f *fakeX11) atom(name string) uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.atoms[name]; !ok {
+		f.atoms[name] = uint32(100 + len(f.atoms))
+	}
+	return f.atoms[name]
+}
+
+func (f *fakeX11) reply(value uint32) {
+	reply := make([]byte, 32)
+	reply[0] = x11Reply
+	binary.LittleEndian.PutUint32(reply[8:], value)
+	f.conn.Write(reply)
+}
+
+func (f *fakeX11) run() {
+	var owner uint32
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(f.conn, header); err != nil {
+			return
+		}
+		body := make([]byte, 4*int(binary.LittleEndian.Uint16(header[2:]))-4)
+		if _, err := io.ReadFull(f.conn, body); err != nil {
+			return
+		}
+		switch header[0] {
+		case x11InternAtom:
+			n := binary.LittleEndian.Uint16(body)
+			f.reply(f.atom(string(body[4 : 4+n])))
+		case x11SetSelectionOwner:
+			owner = binary.LittleEndian.Uint32(body)
+			if f.owner != 0 {
+				owner = f.owner
+			}
+		case x11GetSelectionOwner:
+			f.reply(owner)
+		case x11ChangeProperty, x11SendEvent:
+			f.requests <- append(header, body...)
+		}
+	}
+}
+
+// request returns the next ChangeProperty or SendEvent request and checks its opcode.
+func (f *fakeX11) request(t *testing.T, opcode byte) []byte {
+	t.Helper()
+	select {
+	case req := <-f.requests:
+		if req[0] != opcode {
+			t.Fatalf("Expected request %d, got %d", opcode, req[0])
+		}
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for request %d", opcode)
+		return nil
+	}
+}
+
+// selectionRequest asks the owner for target, to be stored in property 500 of window 99.
+func (f *fakeX11) selectionRequest(target string) {
+	event := make([]byte, 32)
+	event[0] = x11SelectionRequest
+	copy(event[4:], u32s(7, 0, 99, f.atom("CLIPBOARD"), f.atom(target), 500))
+	f.conn.Write(event)
+}
+
+func TestServeX11(t *testing.T) {
+	f, c := newFakeX11(t, 0)
+	ready := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- c.serve("hunter2", func() { close(ready) }) }()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("Serve failed before owning the clipboard: %v", err)
+	}
+
+	// Text targets get the text, in property 500 of the requestor
+	f.selectionRequest("UTF8_STRING")
+	req := f.request(t, x11ChangeProperty)
+	if window, property := binary.LittleEndian.Uint32(req[4:]), binary.LittleEndian.Uint32(req[8:]); window != 99 || property != 500 {
+		t.Fatalf("Expected property 500 of window 99, got %d of %d", property, window)
+	}
+	if n := binary.LittleEndian.Uint32(req[20:]); string(req[24:24+n]) != "hunter2" {
+		t.Fatalf("Expected the text, got %q", req[24:24+n])
+	}
+	notify := f.request(t, x11SendEvent)
+	if notify[12] != x11SelectionNotify || binary.LittleEndian.Uint32(notify[12+20:]) != 500 {
+		t.Fatalf("Expected a SelectionNotify for property 500, got %v", notify[12:])
+	}
+
+	// The sensitive hints are offered as well
+	f.selectionRequest(kdePasswordHintTarget)
+	req = f.request(t, x11ChangeProperty)
+	if n := binary.LittleEndian.Uint32(req[20:]); string(req[24:24+n]) != "secret" {
+		t.Fatalf("Expected the password manager hint, got %q", req[24:24+n])
+	}
+	f.request(t, x11SendEvent)
+
+	f.selectionRequest("TARGETS")
+	req = f.request(t, x11ChangeProperty)
+	if n := binary.LittleEndian.Uint32(req[20:]); int(n) != 1+len(offeredTargets()) {
+		t.Fatalf("Expected TARGETS and %d targets, got %d atoms", len(offeredTargets()), n)
+	}
+	f.request(t, x11SendEvent)
+
+	// Targets that are not offered are refused with property None
+	f.selectionRequest("image/png")
+	notify = f.request(t, x11SendEvent)
+	if binary.LittleEndian.Uint32(notify[12+20:]) != x11AtomNone {
+		t.Fatal("Expected an unknown target to be refused")
+	}
+
+	clear := make([]byte, 32)
+	clear[0] = x11SelectionClear
+	f.conn.Write(clear)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected serve to end when the selection is taken over, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not end after SelectionClear")
+	}
+}
+
+func TestServeX11SelectionKept(t *testing.T) {
+	_, c := newFakeX11(t, 42)
+	err := c.serve("hunter2", func() { t.Fatal("ready should not be called without the selection") })
+	if err == nil {
+		t.Fatal("Expected serve to fail when another client keeps the selection")
+	}
+}